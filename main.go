@@ -6,6 +6,7 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"harness-community/drone-test-result-aggregator/plugin"
 
@@ -31,7 +32,8 @@ func main() {
 	}
 
 	if err := plugin.Exec(context.Background(), args); err != nil {
-		logrus.Fatalln(err)
+		logrus.Errorln(err)
+		os.Exit(plugin.ExitCode(err))
 	}
 }
 
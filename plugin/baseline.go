@@ -0,0 +1,346 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	PreviousBuildStrategy = "previous"
+	TargetBranchStrategy  = "target_branch"
+
+	BuildBaseline   = "build"
+	TagBaseline     = "tag"
+	SemverBaseline  = "semver"
+	ReleaseBaseline = "release"
+	CommitBaseline  = "commit"
+	PinnedBaseline  = "pinned"
+)
+
+// Baseline is a stored build the current build is compared against, along
+// with the selector (for example "previous" or "tag:v1.2.0") that chose it.
+type Baseline struct {
+	Selector string `json:"selector"`
+	BuildId  int    `json:"build_id"`
+}
+
+func (b Baseline) Label() string {
+	return fmt.Sprintf("%s (build %d)", b.Selector, b.BuildId)
+}
+
+// GetBaselineSelectors returns the baselines requested by
+// PLUGIN_COMPARE_BASELINES. Without it, PLUGIN_COMPARE_BUILD_ID or else the
+// single PLUGIN_COMPARE_STRATEGY is used.
+func GetBaselineSelectors(args Args) []string {
+	var selectors []string
+	for _, selector := range strings.Split(args.CompareBaselines, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+	if len(selectors) > 0 {
+		return selectors
+	}
+
+	if args.CompareBuildId != "" {
+		return []string{BuildBaseline + ":" + args.CompareBuildId}
+	}
+	if args.CompareStrategy == "" {
+		return []string{PreviousBuildStrategy}
+	}
+	return []string{args.CompareStrategy}
+}
+
+// ResolveBaselines resolves every requested baseline selector to a stored
+// build number. It fails on the first selector that matches no build.
+func ResolveBaselines(ctx context.Context, store ResultStore, query BuildQuery, currentBuildId string, args Args) ([]Baseline, error) {
+	currentBuild, err := strconv.Atoi(currentBuildId)
+	if err != nil {
+		logrus.Println("Invalid currentBuildId: ", currentBuildId)
+		return nil, fmt.Errorf("invalid currentBuildId: %s", currentBuildId)
+	}
+
+	records, err := store.ListBuilds(ctx, query)
+	if err != nil {
+		logrus.Println("Error listing builds: ", err)
+		return nil, fmt.Errorf("failed to list builds: %w", err)
+	}
+
+	var baselines []Baseline
+	for _, selector := range GetBaselineSelectors(args) {
+		buildId, err := ResolveBaseline(records, currentBuild, selector, args)
+		if err != nil {
+			logrus.Println("Error resolving baseline ", selector, ": ", err)
+			return nil, err
+		}
+		fmt.Println("Baseline ", selector, " resolved to build ID: ", buildId)
+		baselines = append(baselines, Baseline{Selector: selector, BuildId: buildId})
+	}
+	return baselines, nil
+}
+
+// ResolveBaseline picks the build for a single selector among the stored
+// records. Selectors take the form kind or kind:value, see the
+// COMPARISON_README for the supported kinds.
+func ResolveBaseline(records []BuildRecord, currentBuild int, selector string, args Args) (int, error) {
+	kind, value, _ := strings.Cut(selector, ":")
+
+	switch kind {
+	case BuildBaseline:
+		buildId, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("error converting previous build ID to int: %w", err)
+		}
+		return buildId, nil
+
+	case PreviousBuildStrategy:
+		if id, ok := latestBuildBefore(records, currentBuild, func(BuildRecord) bool { return true }); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no previous build ID found for %d", currentBuild)
+
+	case TargetBranchStrategy:
+		branch := value
+		if branch == "" {
+			branch = args.BaselineBranch
+		}
+		if branch == "" {
+			branch = args.Commit.Target
+		}
+		if branch == "" {
+			return 0, fmt.Errorf("compare strategy %s needs DRONE_COMMIT_TARGET or baseline_branch", TargetBranchStrategy)
+		}
+		if id, ok := latestBuildBefore(records, currentBuild, func(record BuildRecord) bool {
+			return IsBranchBaseline(record, branch)
+		}); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no successful build found on branch %s before build %d", branch, currentBuild)
+
+	case TagBaseline:
+		if id, ok := latestBuildBefore(records, currentBuild, func(record BuildRecord) bool {
+			return record.Tags["tag"] != "" && (value == "" || record.Tags["tag"] == value)
+		}); ok {
+			return id, nil
+		}
+		if value == "" {
+			return 0, fmt.Errorf("no tag build found before build %d", currentBuild)
+		}
+		return 0, fmt.Errorf("no build found for git tag %s", value)
+
+	case SemverBaseline:
+		if value == "" {
+			return 0, fmt.Errorf("baseline %s needs a version, for example semver:1.2.0", SemverBaseline)
+		}
+		if id, ok := latestBuildBefore(records, currentBuild, func(record BuildRecord) bool {
+			return record.Tags["semver"] != "" && CompareSemver(record.Tags["semver"], value) == 0
+		}); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no build found for semver %s", value)
+
+	case ReleaseBaseline:
+		if id, ok := latestRelease(records, currentBuild); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no release build found before build %d", currentBuild)
+
+	case CommitBaseline:
+		if value == "" {
+			return 0, fmt.Errorf("baseline %s needs a commit SHA, for example commit:8f51ad7", CommitBaseline)
+		}
+		if id, ok := latestBuildBefore(records, currentBuild, func(record BuildRecord) bool {
+			commit := strings.ToLower(record.Tags["commit"])
+			return commit != "" && strings.HasPrefix(commit, strings.ToLower(value))
+		}); ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no build found for commit %s, make sure the commit pipeline tag is stored", value)
+
+	case PinnedBaseline:
+		if id, ok := latestBuildBefore(records, currentBuild, func(record BuildRecord) bool {
+			return record.Tags["baseline"] != "" && (value == "" || record.Tags["baseline"] == value)
+		}); ok {
+			return id, nil
+		}
+		if value == "" {
+			return 0, fmt.Errorf("no pinned baseline found before build %d", currentBuild)
+		}
+		return 0, fmt.Errorf("no pinned baseline %s found", value)
+	}
+
+	return 0, fmt.Errorf("compare baseline %s not supported", selector)
+}
+
+// IsBranchBaseline reports whether a stored build can serve as the baseline
+// of branch: it ran on that branch, was not itself a pull request build, and
+// had not failed when its results were stored. Builds stored without a
+// buildStatus tag are accepted.
+func IsBranchBaseline(record BuildRecord, branch string) bool {
+	if record.Tags["branch"] != branch || record.Tags["event"] == "pull_request" {
+		return false
+	}
+	status := record.Tags["buildStatus"]
+	return status == "" || status == "success"
+}
+
+// latestBuildBefore returns the highest build number below currentBuild
+// among the records accepted by match.
+func latestBuildBefore(records []BuildRecord, currentBuild int, match func(BuildRecord) bool) (int, bool) {
+	latest := 0
+	found := false
+	for _, record := range records {
+		buildId, err := strconv.Atoi(record.BuildId)
+		if err != nil {
+			logrus.Println("Error converting buildId ", record.BuildId, "to int error: ", err)
+			continue
+		}
+		if buildId >= currentBuild || !match(record) {
+			continue
+		}
+		if !found || buildId > latest {
+			latest = buildId
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// latestRelease returns the build with the highest semver below
+// currentBuild, ignoring pre-releases. Ties go to the later build.
+func latestRelease(records []BuildRecord, currentBuild int) (int, bool) {
+	latest, latestVersion := 0, ""
+	for _, record := range records {
+		version := record.Tags["semver"]
+		if version == "" || strings.Contains(version, "-") {
+			continue
+		}
+		buildId, err := strconv.Atoi(record.BuildId)
+		if err != nil || buildId >= currentBuild {
+			continue
+		}
+		cmp := CompareSemver(version, latestVersion)
+		if latestVersion == "" || cmp > 0 || (cmp == 0 && buildId > latest) {
+			latest, latestVersion = buildId, version
+		}
+	}
+	return latest, latestVersion != ""
+}
+
+// CompareSemver compares two semantic versions, ignoring a leading "v" and
+// build metadata. It returns -1, 0 or 1. Pre-release identifiers are
+// compared as in the semver spec, and a release ranks above its pre-releases.
+func CompareSemver(a, b string) int {
+	aCore, aPre := splitSemver(a)
+	bCore, bPre := splitSemver(b)
+
+	for i := 0; i < 3; i++ {
+		if aCore[i] != bCore[i] {
+			if aCore[i] < bCore[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+
+	aIds, bIds := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aIds) && i < len(bIds); i++ {
+		if cmp := comparePreReleaseId(aIds[i], bIds[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	switch {
+	case len(aIds) < len(bIds):
+		return -1
+	case len(aIds) > len(bIds):
+		return 1
+	}
+	return 0
+}
+
+func splitSemver(version string) ([3]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+	core, preRelease, _ := strings.Cut(version, "-")
+
+	var parts [3]int
+	for i, part := range strings.SplitN(core, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts, preRelease
+}
+
+func comparePreReleaseId(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		if aNum == bNum {
+			return 0
+		}
+		if aNum < bNum {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// BaselineComparison holds the differences between the current build and
+// one baseline.
+type BaselineComparison struct {
+	Baseline Baseline           `json:"baseline"`
+	Diffs    []ResultDiff       `json:"diffs"`
+	Modules  []ModuleComparison `json:"modules,omitempty"`
+}
+
+// CompareWithBaselines compares the current build with each baseline and
+// prints a table per baseline.
+func CompareWithBaselines(ctx context.Context, store ResultStore, query BuildQuery, currentBuildId string, baselines []Baseline) ([]BaselineComparison, error) {
+	currentValues, err := store.FetchBuild(ctx, query, currentBuildId)
+	if err != nil {
+		fmt.Println("CompareWithBaselines Error fetching current build values: ", err)
+		return nil, fmt.Errorf("error fetching current build values: %w", err)
+	}
+	fillDerivedFields(currentValues)
+
+	var comparisons []BaselineComparison
+	for _, baseline := range baselines {
+		baselineValues, err := store.FetchBuild(ctx, query, strconv.Itoa(baseline.BuildId))
+		if err != nil {
+			fmt.Println("CompareWithBaselines Error fetching baseline build values: ", err)
+			return nil, fmt.Errorf("error fetching baseline %s values: %w", baseline.Selector, err)
+		}
+
+		fillDerivedFields(baselineValues)
+
+		fmt.Println("")
+		fmt.Println("Comparison results with " + baseline.Label() + ":")
+		ShowDiffAsTable(currentValues, baselineValues)
+		fmt.Println("")
+
+		diffs := ComputeResultDiffs(currentValues, baselineValues)
+		for i := range diffs {
+			diffs[i].Baseline, diffs[i].BaselineBuildId = baseline.Selector, baseline.BuildId
+		}
+		comparisons = append(comparisons, BaselineComparison{Baseline: baseline, Diffs: diffs})
+	}
+	return comparisons, nil
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+const DefaultCardSchema = "https://raw.githubusercontent.com/harness-community/drone-test-result-aggregator/main/card.json"
+
+// CardData is bound to the adaptive card template in card.json.
+type CardData struct {
+	Tool     string     `json:"tool"`
+	BuildId  string     `json:"build_id"`
+	Group    string     `json:"group"`
+	Status   string     `json:"status"`
+	Summary  []CardFact `json:"summary"`
+	Coverage []CardFact `json:"coverage"`
+	Baseline string     `json:"baseline"`
+	Deltas   []CardFact `json:"deltas"`
+}
+
+type CardFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewCardData builds the card from the report: the totals, the coverage
+// percentages for jacoco and the deltas against the first baseline.
+func NewCardData(report BuildReport) CardData {
+	card := CardData{
+		Tool:     report.Tool,
+		BuildId:  report.BuildId,
+		Group:    report.Group,
+		Status:   "passed",
+		Summary:  []CardFact{},
+		Coverage: []CardFact{},
+		Deltas:   []CardFact{},
+	}
+	if failed, _ := CountFailedTests(report.Result.Fields); failed > 0 || !GatesPassed(report.Gates) {
+		card.Status = "failed"
+	}
+
+	current := map[string]float64{}
+	var fieldNames []string
+	for name, value := range report.Result.Fields {
+		current[name], _ = toFloat64(value)
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	for _, name := range fieldNames {
+		card.Summary = append(card.Summary, CardFact{Name: name, Value: formatMarkdownNumber(current[name])})
+	}
+
+	if report.Tool == JacocoTool {
+		fillDerivedFields(current)
+		for _, coverageType := range jacocoCoverageTypes {
+			card.Coverage = append(card.Coverage, CardFact{
+				Name:  coverageType.Label,
+				Value: fmt.Sprintf("%.2f%%", current[coverageType.Field+CoverageFieldSuffix]),
+			})
+		}
+	}
+
+	if len(report.Comparisons) > 0 {
+		comparison := report.Comparisons[0]
+		card.Baseline = comparison.Baseline.Label()
+		for _, diff := range comparison.Diffs {
+			card.Deltas = append(card.Deltas, CardFact{
+				Name: diff.FieldName,
+				Value: fmt.Sprintf("%s %s (%.2f%%)", markdownArrow(diff.Difference),
+					formatMarkdownDelta(diff.Difference), diff.PercentageDifference),
+			})
+		}
+	}
+	return card
+}
+
+// WriteCard writes the card for the Drone and Harness build page to path.
+// /dev/stdout and /dev/stderr get the encoded card escape sequence instead.
+func WriteCard(path, schema string, card CardData) error {
+	if schema == "" {
+		schema = DefaultCardSchema
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"schema": schema,
+		"data":   card,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch path {
+	case "/dev/stdout":
+		return writeCardTo(os.Stdout, data)
+	case "/dev/stderr":
+		return writeCardTo(os.Stderr, data)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeCardTo(out io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	_, err := io.WriteString(out, "\u001B]1338;"+encoded+"\u001B]0m\n")
+	return err
+}
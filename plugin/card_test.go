@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCard(t *testing.T) {
+	report := BuildReport{
+		Tool:    JacocoTool,
+		BuildId: mockBuildNumber,
+		Result: AggregateResult{
+			Fields: map[string]interface{}{"line_covered_sum": 90.0, "line_missed_sum": 10.0},
+		},
+		Comparisons: []BaselineComparison{{
+			Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 200},
+			Diffs: ComputeResultDiffs(map[string]float64{"line_covered_sum": 90},
+				map[string]float64{"line_covered_sum": 80}),
+		}},
+	}
+
+	path := filepath.Join(t.TempDir(), "card.json")
+	if err := WriteCard(path, "", NewCardData(report)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading card: %v", err)
+	}
+	var card struct {
+		Schema string   `json:"schema"`
+		Data   CardData `json:"data"`
+	}
+	if err := json.Unmarshal(content, &card); err != nil {
+		t.Fatalf("Error decoding card: %v", err)
+	}
+
+	if card.Schema != DefaultCardSchema || card.Data.Status != "passed" || card.Data.BuildId != mockBuildNumber {
+		t.Errorf("Unexpected card header: %+v", card)
+	}
+	if len(card.Data.Coverage) != len(jacocoCoverageTypes) || card.Data.Coverage[2] != (CardFact{Name: "Line", Value: "90.00%"}) {
+		t.Errorf("Unexpected card coverage: %+v", card.Data.Coverage)
+	}
+	if card.Data.Baseline != "previous (build 200)" ||
+		card.Data.Deltas[0] != (CardFact{Name: "line_covered_sum", Value: "⬆️ +10 (12.50%)"}) {
+		t.Errorf("Unexpected card deltas: %+v", card.Data.Deltas)
+	}
+}
+
+func TestNewCardDataStatus(t *testing.T) {
+	tests := []struct {
+		report   BuildReport
+		expected string
+	}{
+		{BuildReport{Tool: TestNgTool, Result: AggregateResult{Fields: map[string]interface{}{"total_cases": 5, "total_failed": 1}}}, "failed"},
+		{BuildReport{Tool: NunitTool, Result: AggregateResult{Fields: map[string]interface{}{"total_cases": 5, "total_failed": 0}}}, "passed"},
+		{BuildReport{Tool: JunitTool, Result: AggregateResult{Fields: map[string]interface{}{"total_tests": 5, "failed_tests": 0, "errors_count": 2}}}, "failed"},
+		{BuildReport{Tool: JacocoTool, Gates: []GateResult{{Passed: false}}}, "failed"},
+	}
+	for _, test := range tests {
+		if status := NewCardData(test.report).Status; status != test.expected {
+			t.Errorf("Expected status %s for %s, got %s", test.expected, test.report.Tool, status)
+		}
+	}
+}
+
+func TestWriteCardTo(t *testing.T) {
+	var sb strings.Builder
+	if err := writeCardTo(&sb, []byte(`{"schema":"s"}`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "\u001B]1338;" + base64.StdEncoding.EncodeToString([]byte(`{"schema":"s"}`)) + "\u001B]0m\n"
+	if sb.String() != expected {
+		t.Errorf("Unexpected card escape sequence %q", sb.String())
+	}
+}
@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CsvDiffFormat      = "csv"
+	JsonDiffFormat     = "json"
+	MarkdownDiffFormat = "markdown"
+
+	DefaultDiffFileName              = "build_results_diff"
+	TestResultsDiffCsvFileOutputVar  = "TEST_RESULTS_DIFF_CSV_FILE"
+	TestResultsDiffJsonFileOutputVar = "TEST_RESULTS_DIFF_JSON_FILE"
+	TestResultsDiffMdFileOutputVar   = "TEST_RESULTS_DIFF_MARKDOWN_FILE"
+
+	HigherIsBetter   = "higher_is_better"
+	LowerIsBetter    = "lower_is_better"
+	NeutralDirection = "neutral"
+
+	ImprovedChange  = "improved"
+	RegressedChange = "regressed"
+	UnchangedChange = "unchanged"
+	ChangedChange   = "changed"
+)
+
+var resultDiffHeader = []string{"Field Name", "Current", "Previous", "Difference", "Percentage Difference", "Direction", "Change"}
+
+var diffFormatExtensions = map[string]string{
+	CsvDiffFormat:      ".csv",
+	JsonDiffFormat:     ".json",
+	MarkdownDiffFormat: ".md",
+}
+
+var diffFormatOutputVars = map[string]string{
+	CsvDiffFormat:      TestResultsDiffCsvFileOutputVar,
+	JsonDiffFormat:     TestResultsDiffJsonFileOutputVar,
+	MarkdownDiffFormat: TestResultsDiffMdFileOutputVar,
+}
+
+var fieldDirections = map[string]string{
+	"total_tests":    HigherIsBetter,
+	"passed_tests":   HigherIsBetter,
+	"total_cases":    HigherIsBetter,
+	"total_passed":   HigherIsBetter,
+	"failed_tests":   LowerIsBetter,
+	"errors_count":   LowerIsBetter,
+	"skipped_tests":  LowerIsBetter,
+	"total_failed":   LowerIsBetter,
+	"total_skipped":  LowerIsBetter,
+	"duration_ms":    LowerIsBetter,
+	PassRateField:    HigherIsBetter,
+	FailureRateField: LowerIsBetter,
+	SkipRateField:    LowerIsBetter,
+	FlakyTestsField:  LowerIsBetter,
+	RerunTestsField:  LowerIsBetter,
+}
+
+// FieldDirection tells whether an increase of the field is an improvement.
+// Coverage sums follow their suffix: more covered is better, more missed is
+// worse, and the totals only follow the size of the code base. Coverage
+// percentages are higher is better.
+func FieldDirection(field string) string {
+	if direction, ok := fieldDirections[field]; ok {
+		return direction
+	}
+	switch {
+	case strings.HasSuffix(field, "_covered_sum"), strings.HasSuffix(field, CoverageFieldSuffix):
+		return HigherIsBetter
+	case strings.HasSuffix(field, "_missed_sum"):
+		return LowerIsBetter
+	}
+	return NeutralDirection
+}
+
+func ChangeForDirection(direction string, difference float64) string {
+	switch {
+	case difference == 0:
+		return UnchangedChange
+	case direction == HigherIsBetter && difference > 0, direction == LowerIsBetter && difference < 0:
+		return ImprovedChange
+	case direction == NeutralDirection:
+		return ChangedChange
+	}
+	return RegressedChange
+}
+
+// GetDiffFormats parses the comma separated PLUGIN_DIFF_FORMAT, CSV when empty.
+func GetDiffFormats(formats string) ([]string, error) {
+	var diffFormats []string
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "md" {
+			format = MarkdownDiffFormat
+		}
+		if format == "" {
+			continue
+		}
+		if _, ok := diffFormatExtensions[format]; !ok {
+			return nil, fmt.Errorf("diff format %s not supported, use csv, json or markdown", format)
+		}
+		diffFormats = append(diffFormats, format)
+	}
+	if len(diffFormats) == 0 {
+		return []string{CsvDiffFormat}, nil
+	}
+	return diffFormats, nil
+}
+
+// ExportComparisons writes the comparisons in every requested format to
+// diff_output_dir and exports each path. TEST_RESULTS_DIFF_FILE points to the
+// first format.
+func ExportComparisons(args Args, comparisons []BaselineComparison) error {
+	formats, err := GetDiffFormats(args.DiffFormat)
+	if err != nil {
+		return err
+	}
+
+	fileName := args.DiffFileName
+	if fileName == "" {
+		fileName = DefaultDiffFileName
+	}
+	for _, extension := range diffFormatExtensions {
+		fileName = strings.TrimSuffix(fileName, extension)
+	}
+	if args.DiffOutputDir != "" {
+		if err := os.MkdirAll(args.DiffOutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create diff output directory: %w", err)
+		}
+	}
+
+	for i, format := range formats {
+		var content string
+		switch format {
+		case CsvDiffFormat:
+			content, err = ComparisonsToCsv(comparisons)
+		case JsonDiffFormat:
+			content, err = ComparisonsToJson(comparisons)
+		case MarkdownDiffFormat:
+			content = ComparisonsToMarkdown(comparisons)
+		}
+		if err != nil {
+			logrus.Println("Unable to render comparison results as ", format, err)
+			return err
+		}
+
+		path := filepath.Join(args.DiffOutputDir, fileName+diffFormatExtensions[format])
+		if err := ExportComparisonResults(path, content, diffFormatOutputVars[format]); err != nil {
+			return err
+		}
+		if i == 0 {
+			if err := WriteToEnvVariable(TestResultsDiffFileOutputVar, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ComparisonsToCsv renders the comparisons as CSV, one row per field. With
+// several baselines, every row starts with the baseline it belongs to.
+func ComparisonsToCsv(comparisons []BaselineComparison) (string, error) {
+	var csvBuffer strings.Builder
+	writer := csv.NewWriter(&csvBuffer)
+
+	header := resultDiffHeader
+	if len(comparisons) > 1 {
+		header = append([]string{"Baseline"}, header...)
+	}
+	records := [][]string{header}
+
+	for _, comparison := range comparisons {
+		for _, diff := range comparison.Diffs {
+			record := resultDiffRecord(diff)
+			if len(comparisons) > 1 {
+				record = append([]string{comparison.Baseline.Selector}, record...)
+			}
+			records = append(records, record)
+		}
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		logrus.Println("Error writing to CSV writer: ", err)
+		return "", err
+	}
+	return csvBuffer.String(), nil
+}
+
+// ComparisonsToJson renders the comparisons as a single list of ResultDiff,
+// each tagged with the baseline it was compared against and, for the module
+// breakdown, with its module.
+func ComparisonsToJson(comparisons []BaselineComparison) (string, error) {
+	diffs := []ResultDiff{}
+	for _, comparison := range comparisons {
+		diffs = append(diffs, comparison.Diffs...)
+		for _, module := range comparison.Modules {
+			diffs = append(diffs, module.Diffs...)
+		}
+	}
+	data, err := json.MarshalIndent(diffs, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func ComparisonsToMarkdown(comparisons []BaselineComparison) string {
+	var sb strings.Builder
+	for _, comparison := range comparisons {
+		fmt.Fprintf(&sb, "### Comparison with %s\n\n", comparison.Baseline.Label())
+		sb.WriteString("| Result Type | Current | Previous | Difference | Percentage Difference | Change |\n")
+		sb.WriteString("|---|---:|---:|---:|---:|---|\n")
+		for _, diff := range comparison.Diffs {
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s %s | %.2f%% | %s |\n", diff.FieldName,
+				formatMarkdownNumber(diff.CurrentBuildValue), formatMarkdownNumber(diff.PreviousBuildValue),
+				markdownArrow(diff.Difference), formatMarkdownDelta(diff.Difference),
+				diff.PercentageDifference, markdownChange(diff.Change))
+		}
+		sb.WriteString("\n")
+		writeMarkdownModuleDiffs(&sb, comparison.Modules)
+	}
+	return sb.String()
+}
+
+// writeMarkdownModuleDiffs lists the fields that changed in each module.
+func writeMarkdownModuleDiffs(sb *strings.Builder, modules []ModuleComparison) {
+	var rows []string
+	for _, module := range modules {
+		for _, diff := range module.Diffs {
+			if diff.Difference == 0 && diff.IsCompareValid {
+				continue
+			}
+			rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s | %s %s | %s |\n", escapeMarkdownCell(module.Module),
+				diff.FieldName, formatMarkdownNumber(diff.CurrentBuildValue), formatMarkdownNumber(diff.PreviousBuildValue),
+				markdownArrow(diff.Difference), formatMarkdownDelta(diff.Difference), markdownChange(diff.Change)))
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	sb.WriteString("#### Changed modules\n\n")
+	sb.WriteString("| Module | Result Type | Current | Previous | Difference | Change |\n")
+	sb.WriteString("|---|---|---:|---:|---:|---|\n")
+	for _, row := range rows {
+		sb.WriteString(row)
+	}
+	sb.WriteString("\n")
+}
+
+func markdownChange(change string) string {
+	switch change {
+	case ImprovedChange:
+		return "✅ improved"
+	case RegressedChange:
+		return "❌ regressed"
+	}
+	return change
+}
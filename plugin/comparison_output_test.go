@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFieldDirection(t *testing.T) {
+	tests := []struct {
+		field      string
+		difference float64
+		direction  string
+		change     string
+	}{
+		{"failed_tests", -1, LowerIsBetter, ImprovedChange},
+		{"passed_tests", -1, HigherIsBetter, RegressedChange},
+		{"line_covered_sum", 5, HigherIsBetter, ImprovedChange},
+		{"branch_missed_sum", 2, LowerIsBetter, RegressedChange},
+		{"line_total_sum", 3, NeutralDirection, ChangedChange},
+		{"total_tests", 0, HigherIsBetter, UnchangedChange},
+	}
+	for _, tt := range tests {
+		direction := FieldDirection(tt.field)
+		if direction != tt.direction {
+			t.Errorf("Expected direction %s for %s, got %s", tt.direction, tt.field, direction)
+		}
+		if change := ChangeForDirection(direction, tt.difference); change != tt.change {
+			t.Errorf("Expected change %s for %s, got %s", tt.change, tt.field, change)
+		}
+	}
+}
+
+func TestGetDiffFormats(t *testing.T) {
+	formats, err := GetDiffFormats("")
+	if err != nil || len(formats) != 1 || formats[0] != CsvDiffFormat {
+		t.Errorf("Expected csv by default, got %v (%v)", formats, err)
+	}
+	formats, err = GetDiffFormats("JSON, md")
+	if err != nil || len(formats) != 2 || formats[0] != JsonDiffFormat || formats[1] != MarkdownDiffFormat {
+		t.Errorf("Expected json and markdown, got %v (%v)", formats, err)
+	}
+	if _, err = GetDiffFormats("xml"); err == nil {
+		t.Errorf("Expected error for an unsupported format")
+	}
+}
+
+func TestExportComparisons(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "diffs")
+	outputFile := filepath.Join(t.TempDir(), "drone_output")
+	t.Setenv("DRONE_OUTPUT", outputFile)
+
+	comparisons := []BaselineComparison{{
+		Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 1},
+		Diffs: ComputeResultDiffs(map[string]float64{"failed_tests": 1, "total_tests": 12},
+			map[string]float64{"failed_tests": 2, "total_tests": 10}),
+	}}
+
+	args := Args{DiffFormat: "json,markdown,csv", DiffOutputDir: outputDir, DiffFileName: "junit_diff.csv"}
+	if err := ExportComparisons(args, comparisons); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "junit_diff.json"))
+	if err != nil {
+		t.Fatalf("Error reading JSON diff: %v", err)
+	}
+	var diffs []ResultDiff
+	if err := json.Unmarshal(content, &diffs); err != nil {
+		t.Fatalf("Error decoding JSON diff: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].FieldName != "failed_tests" || diffs[0].Change != ImprovedChange || diffs[0].Direction != LowerIsBetter {
+		t.Errorf("Unexpected JSON diffs: %+v", diffs)
+	}
+
+	markdown, err := os.ReadFile(filepath.Join(outputDir, "junit_diff.md"))
+	if err != nil || !strings.Contains(string(markdown), "| failed_tests | 1 | 2 | ⬇️ -1 | -50.00% | ✅ improved |") {
+		t.Errorf("Unexpected Markdown diff: %s (%v)", markdown, err)
+	}
+
+	csvContent, err := os.ReadFile(filepath.Join(outputDir, "junit_diff.csv"))
+	if err != nil || !strings.Contains(string(csvContent), "total_tests,12.00,10.00,2.00,20.00%,higher_is_better,improved") {
+		t.Errorf("Unexpected CSV diff: %s (%v)", csvContent, err)
+	}
+
+	outputVars, _ := os.ReadFile(outputFile)
+	if !strings.Contains(string(outputVars), TestResultsDiffFileOutputVar+"="+filepath.Join(outputDir, "junit_diff.json")) {
+		t.Errorf("Expected %s to point to the first format, got %s", TestResultsDiffFileOutputVar, outputVars)
+	}
+}
+
+func TestExportComparisonResultsWriteError(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output.env")
+	t.Setenv("DRONE_OUTPUT", outputFile)
+	// a file in place of the output directory cannot be written into, even as root
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	os.WriteFile(blocked, []byte{}, 0644)
+
+	err := ExportComparisonResults(filepath.Join(blocked, "diff.csv"), "a,b\n", TestResultsDiffFileOutputVar)
+	if err == nil {
+		t.Fatalf("Expected an error for a file that cannot be written")
+	}
+	if output, _ := os.ReadFile(outputFile); strings.Contains(string(output), TestResultsDiffFileOutputVar) {
+		t.Errorf("Expected no output variable for the unwritten file, got %s", output)
+	}
+}
@@ -0,0 +1,86 @@
+package plugin
+
+const (
+	PassRateField       = "pass_rate"
+	FailureRateField    = "failure_rate"
+	SkipRateField       = "skip_rate"
+	CoverageFieldSuffix = "_coverage"
+)
+
+// testCountFields names the count fields each tool stores. Errors are counted
+// as failures, and the passed count is derived when the tool does not store
+// it, as for TestNG.
+var testCountFields = []struct {
+	Total   string
+	Passed  string
+	Failed  string
+	Errors  string
+	Skipped string
+}{
+	{"total_tests", "passed_tests", "failed_tests", "errors_count", "skipped_tests"},
+	{"total_cases", "total_passed", "total_failed", "", "total_skipped"},
+}
+
+// DeriveFields computes the percentage fields from the raw counts: the
+// coverage percentage of every jacoco counter type and the pass, failure and
+// skip rates of the test tools.
+func DeriveFields(values map[string]float64) map[string]float64 {
+	derived := map[string]float64{}
+
+	for _, coverageType := range jacocoCoverageTypes {
+		covered, hasCovered := values[coverageType.Field+"_covered_sum"]
+		missed, hasMissed := values[coverageType.Field+"_missed_sum"]
+		if hasCovered && hasMissed {
+			derived[coverageType.Field+CoverageFieldSuffix] = CalculatePercentage(int(covered), int(missed))
+		}
+	}
+
+	for _, names := range testCountFields {
+		total, exists := values[names.Total]
+		if !exists {
+			continue
+		}
+		failed := values[names.Failed] + values[names.Errors]
+		skipped := values[names.Skipped]
+		passed, hasPassed := values[names.Passed]
+		if !hasPassed {
+			passed = total - failed - skipped
+		}
+		derived[PassRateField] = ratePercentage(passed, total)
+		derived[FailureRateField] = ratePercentage(failed, total)
+		derived[SkipRateField] = ratePercentage(skipped, total)
+		break
+	}
+	return derived
+}
+
+// AddDerivedFields adds the derived percentage fields to the fields of a
+// build, so they are stored and compared alongside the raw counts.
+func AddDerivedFields(fields map[string]interface{}) {
+	values := map[string]float64{}
+	for name, value := range fields {
+		if number, ok := toFloat64(value); ok {
+			values[name] = number
+		}
+	}
+	for name, value := range DeriveFields(values) {
+		fields[name] = value
+	}
+}
+
+// fillDerivedFields adds the derived fields missing from stored values, for
+// builds stored before they existed.
+func fillDerivedFields(values map[string]float64) {
+	for name, value := range DeriveFields(values) {
+		if _, exists := values[name]; !exists {
+			values[name] = value
+		}
+	}
+}
+
+func ratePercentage(count, total float64) float64 {
+	if total == 0 {
+		return 0.0
+	}
+	return count / total * 100
+}
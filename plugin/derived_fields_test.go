@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestDeriveFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string]float64
+		expected map[string]float64
+	}{
+		{
+			name:     "junit counts errors as failures",
+			values:   map[string]float64{"total_tests": 10, "passed_tests": 6, "failed_tests": 1, "errors_count": 1, "skipped_tests": 2},
+			expected: map[string]float64{PassRateField: 60, FailureRateField: 20, SkipRateField: 20},
+		},
+		{
+			name:     "testng derives the passed count",
+			values:   map[string]float64{"total_cases": 8, "total_failed": 2, "total_skipped": 0, "duration_ms": 120},
+			expected: map[string]float64{PassRateField: 75, FailureRateField: 25, SkipRateField: 0},
+		},
+		{
+			name:     "jacoco coverage percentages",
+			values:   map[string]float64{"line_covered_sum": 90, "line_missed_sum": 10, "branch_covered_sum": 0, "branch_missed_sum": 0},
+			expected: map[string]float64{"line_coverage": 90, "branch_coverage": 0},
+		},
+		{
+			name:     "no tests",
+			values:   map[string]float64{"total_cases": 0, "total_passed": 0, "total_failed": 0, "total_skipped": 0},
+			expected: map[string]float64{PassRateField: 0, FailureRateField: 0, SkipRateField: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			derived := DeriveFields(tt.values)
+			if len(derived) != len(tt.expected) {
+				t.Errorf("Expected %d derived fields, got %v", len(tt.expected), derived)
+			}
+			for field, expected := range tt.expected {
+				if math.Abs(derived[field]-expected) > 0.0001 {
+					t.Errorf("Expected %s to be %.2f, got %.2f", field, expected, derived[field])
+				}
+			}
+		})
+	}
+}
+
+func TestDerivedFieldsAreCompared(t *testing.T) {
+	store := NewMemoryResultStore()
+	query := BuildQuery{Measurement: "junit_test_results", PipelineId: "pipe_1"}
+	// build 1 was stored before the derived fields existed
+	err := store.WritePoint(context.Background(), query.Measurement, map[string]string{"pipelineId": "pipe_1", "buildId": "1"},
+		map[string]interface{}{"total_tests": 10, "passed_tests": 8, "failed_tests": 2, "skipped_tests": 0, "errors_count": 0})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, fields := GetJunitDataMaps("pipe_1", "2", TestStats{TestCount: 10, PassCount: 9, FailCount: 1})
+	if fields[PassRateField] != 90.0 {
+		t.Fatalf("Expected pass_rate to be stored, got %v", fields[PassRateField])
+	}
+	err = store.WritePoint(context.Background(), query.Measurement, map[string]string{"pipelineId": "pipe_1", "buildId": "2"}, fields)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	comparisons, err := CompareWithBaselines(context.Background(), store, query, "2", []Baseline{{Selector: PreviousBuildStrategy, BuildId: 1}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, diff := range comparisons[0].Diffs {
+		if diff.FieldName == PassRateField {
+			if diff.PreviousBuildValue != 80 || diff.Difference != 10 || diff.Change != ImprovedChange || !diff.IsCompareValid {
+				t.Errorf("Unexpected pass_rate diff: %+v", diff)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected a pass_rate diff in %+v", comparisons[0].Diffs)
+}
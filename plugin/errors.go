@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Error categories returned by Exec. Each one has its own exit code, so a
+// pipeline can tell failed tests apart from an unreachable result store.
+var (
+	ErrTestsFailed = errors.New("tests failed")
+	ErrGateFailed  = errors.New("quality gates failed")
+	ErrNoReports   = errors.New("no reports")
+	ErrParse       = errors.New("report parse error")
+	ErrStore       = errors.New("result store error")
+)
+
+const (
+	// ExitCodeError is the exit code of any other error, such as an invalid
+	// setting.
+	ExitCodeError       = 1
+	ExitCodeTestsFailed = 2
+	ExitCodeGateFailed  = 3
+	ExitCodeNoReports   = 4
+	ExitCodeParse       = 5
+	ExitCodeStore       = 6
+)
+
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrTestsFailed, ExitCodeTestsFailed},
+	{ErrGateFailed, ExitCodeGateFailed},
+	{ErrNoReports, ExitCodeNoReports},
+	{ErrParse, ExitCodeParse},
+	{ErrStore, ExitCodeStore},
+}
+
+// ExitCode maps the error returned by Exec to the exit code of the plugin.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	for _, exitCode := range exitCodes {
+		if errors.Is(err, exitCode.err) {
+			return exitCode.code
+		}
+	}
+	return ExitCodeError
+}
+
+// StoreErrorAsWarning logs result store errors as warnings and drops them
+// when warn_on_store_error is set. Any other error is returned as is.
+func StoreErrorAsWarning(args Args, err error) error {
+	if err == nil || !args.WarnOnStoreError || !errors.Is(err, ErrStore) {
+		return err
+	}
+	logrus.Warnln("Ignoring result store error: ", err)
+	return nil
+}
+
+// categorizedStore marks every error of the wrapped store as ErrStore.
+type categorizedStore struct {
+	ResultStore
+}
+
+func storeError(err error) error {
+	if err == nil || errors.Is(err, ErrStore) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrStore, err)
+}
+
+func (s categorizedStore) WritePoint(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}) error {
+	return storeError(s.ResultStore.WritePoint(ctx, measurement, tags, fields))
+}
+
+func (s categorizedStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	values, err := s.ResultStore.FetchBuild(ctx, query, buildId)
+	return values, storeError(err)
+}
+
+func (s categorizedStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	records, err := s.ResultStore.ListBuilds(ctx, query)
+	return records, storeError(err)
+}
@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingStore fails every call, as an unreachable database would.
+type failingStore struct{}
+
+func (failingStore) WritePoint(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingStore) Close() {}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("store type csv not supported"), ExitCodeError},
+		{fmt.Errorf("%w: 2 failed tests in the junit reports", ErrTestsFailed), ExitCodeTestsFailed},
+		{ErrGateFailed, ExitCodeGateFailed},
+		{CheckParsedReports(ParseOptions{}, 0, 0, nil), ExitCodeNoReports},
+		{CheckParsedReports(ParseOptions{Mode: StrictParseMode}, 2, 1, []SkippedFile{{Path: "a.xml"}}), ExitCodeParse},
+		{fmt.Errorf("error fetching current build values: %w", storeError(errors.New("timeout"))), ExitCodeStore},
+	}
+	for _, tt := range tests {
+		if code := ExitCode(tt.err); code != tt.code {
+			t.Errorf("Expected exit code %d for %v, got %d", tt.code, tt.err, code)
+		}
+	}
+}
+
+func TestCategorizedStore(t *testing.T) {
+	store := categorizedStore{failingStore{}}
+	err := store.WritePoint(context.Background(), JunitTool, nil, nil)
+	if !errors.Is(err, ErrStore) || err.Error() != "result store error: connection refused" {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+	if _, err := store.FetchBuild(context.Background(), BuildQuery{}, "1"); !errors.Is(err, ErrStore) {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+	if _, err := store.ListBuilds(context.Background(), BuildQuery{}); !errors.Is(err, ErrStore) {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+}
+
+func TestStoreResultsWarnOnStoreError(t *testing.T) {
+	reportsDir := t.TempDir()
+	os.WriteFile(filepath.Join(reportsDir, "TEST-run1.xml"), []byte(firstRunJunitXml), 0644)
+	t.Setenv("HARNESS_PIPELINE_ID", "pipeline")
+	t.Setenv("HARNESS_BUILD_ID", "1")
+	t.Setenv("DRONE_OUTPUT", filepath.Join(t.TempDir(), "output.env"))
+
+	args := Args{Tool: JunitTool, ReportsDir: reportsDir, IncludePattern: "*.xml", ModuleBreakdown: true}
+	store := categorizedStore{failingStore{}}
+	if _, err := StoreResults(context.Background(), args, store); ExitCode(err) != ExitCodeStore {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+
+	args.WarnOnStoreError = true
+	result, err := StoreResults(context.Background(), args, store)
+	if err != nil {
+		t.Errorf("Expected the store error to be a warning, got %v", err)
+	}
+	if len(result.Modules) != 1 {
+		t.Errorf("Expected the module breakdown after the store error, got %+v", result.Modules)
+	}
+	if err := StoreErrorAsWarning(args, errors.New("invalid pattern")); err == nil {
+		t.Errorf("Expected errors other than store errors to be returned")
+	}
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const DefaultResultsStoreFile = ".test-results-aggregator/results.jsonl"
+
+// FileResultStore is a ResultStore that appends one JSON document per point
+// to a local file. Keeping the file in a cached directory or in the workspace
+// lets builds compare against each other without a database.
+type FileResultStore struct {
+	Path string
+}
+
+type fileStorePoint struct {
+	Measurement string             `json:"measurement"`
+	Time        time.Time          `json:"time"`
+	Tags        map[string]string  `json:"tags"`
+	Fields      map[string]float64 `json:"fields"`
+}
+
+func NewFileResultStore(path string) *FileResultStore {
+	if path == "" {
+		path = DefaultResultsStoreFile
+	}
+	return &FileResultStore{Path: path}
+}
+
+func (f *FileResultStore) WritePoint(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}) error {
+	return f.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (f *FileResultStore) WritePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	point := fileStorePoint{
+		Measurement: measurement,
+		Time:        pointTime.UTC(),
+		Tags:        tags,
+		Fields:      map[string]float64{},
+	}
+	for key, value := range fields {
+		field, ok := toFloat64(value)
+		if !ok {
+			return fmt.Errorf("unsupported value %v for field %s", value, key)
+		}
+		point.Fields[key] = field
+	}
+
+	line, err := json.Marshal(point)
+	if err != nil {
+		return fmt.Errorf("failed to encode point: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create results store directory: %w", err)
+	}
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open results store file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write to results store file: %w", err)
+	}
+	return nil
+}
+
+func (f *FileResultStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	records, err := f.ListBuilds(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return fieldsForBuild(records, buildId), nil
+}
+
+func (f *FileResultStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	file, err := os.Open(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results store file: %w", err)
+	}
+	defer file.Close()
+
+	var records []BuildRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var point fileStorePoint
+		if err := json.Unmarshal(scanner.Bytes(), &point); err != nil {
+			return nil, fmt.Errorf("invalid results store entry at %s:%d: %w", f.Path, lineNumber, err)
+		}
+		if !matchesBuildQuery(point.Measurement, point.Tags, query) {
+			continue
+		}
+		records = append(records, BuildRecord{
+			BuildId: point.Tags["buildId"],
+			Time:    point.Time,
+			Tags:    point.Tags,
+			Fields:  point.Fields,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read results store file: %w", err)
+	}
+	return records, nil
+}
+
+func (f *FileResultStore) Close() {}
@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileResultStoreRoundTrip(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cache", "results.jsonl")
+	query := BuildQuery{Measurement: JacocoTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	store := NewFileResultStore(storePath)
+	for buildId, covered := range map[string]float64{"7": 100, "8": 120} {
+		tags := map[string]string{"pipelineId": mockPipelineId, "buildId": buildId}
+		fields := map[string]interface{}{"line_covered_sum": covered, "line_missed_sum": 10}
+		if err := PersistResults(context.Background(), store, JacocoTool, "suite_01", tags, fields); err != nil {
+			t.Fatalf("Error persisting build %s: %v", buildId, err)
+		}
+	}
+
+	reopened := NewFileResultStore(storePath)
+	baselines, err := ResolveBaselines(context.Background(), reopened, query, "8", Args{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if baselines[0].BuildId != 7 {
+		t.Errorf("Expected previous build 7, got %d", baselines[0].BuildId)
+	}
+
+	fields, err := reopened.FetchBuild(context.Background(), query, "8")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fields["line_covered_sum"] != 120 || fields["line_missed_sum"] != 10 {
+		t.Errorf("Unexpected fields for build 8: %v", fields)
+	}
+}
+
+func TestFileResultStoreMissingFile(t *testing.T) {
+	store := NewFileResultStore(filepath.Join(t.TempDir(), "missing.jsonl"))
+	records, err := store.ListBuilds(context.Background(), BuildQuery{Measurement: JunitTool})
+	if err != nil {
+		t.Fatalf("Expected no error for a missing store file, got %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no records, got %d", len(records))
+	}
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultHtmlReportFile          = "test_results_report.html"
+	TestResultsHtmlReportOutputVar = "TEST_RESULTS_HTML_REPORT"
+
+	trendChartWidth   = 240
+	trendChartHeight  = 60
+	trendChartPadding = 4
+)
+
+type htmlReportField struct {
+	Name  string
+	Value string
+}
+
+type htmlReportPackage struct {
+	Name        string
+	Instruction string
+	Branch      string
+	Line        string
+	Method      string
+}
+
+type htmlReportData struct {
+	BuildReport
+	Fields   []htmlReportField
+	Packages []htmlReportPackage
+}
+
+var htmlReportFuncs = template.FuncMap{
+	"fixed": func(value float64) string {
+		return fmt.Sprintf("%.2f", value)
+	},
+	"signed": func(value float64) string {
+		return fmt.Sprintf("%+.2f", value)
+	},
+	"chartPoints": TrendChartPoints,
+	"lastPoint": func(values []float64) string {
+		points := strings.Fields(TrendChartPoints(values))
+		if len(points) == 0 {
+			return ""
+		}
+		return points[len(points)-1]
+	},
+	"split": strings.Split,
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(htmlReportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Tool}} test results - build {{.BuildId}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; margin-top: .5em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; }
+th { background: #f6f8fa; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.meta { color: #59636e; }
+.improved, .passed { color: #1a7f37; }
+.regressed, .failed { color: #cf222e; }
+pre { white-space: pre-wrap; margin: 0; font-size: .85em; }
+svg polyline { fill: none; stroke: #0969da; stroke-width: 2; }
+svg circle { fill: #0969da; }
+</style>
+</head>
+<body>
+<h1>{{.Tool}} test results</h1>
+<p class="meta">Pipeline {{.PipelineId}} &middot; Build {{.BuildId}}{{if .Group}} &middot; Group {{.Group}}{{end}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Result Type</th><th>Value</th></tr>
+{{range .Fields}}<tr><td>{{.Name}}</td><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+
+{{if .Result.Failures}}
+<h2>Failures ({{len .Result.Failures}})</h2>
+<table>
+<tr><th>Class</th><th>Test</th><th>Status</th><th>Duration (ms)</th><th>Message</th></tr>
+{{range .Result.Failures}}<tr><td>{{.ClassName}}</td><td>{{.Name}}</td><td>{{.Status}}</td><td class="num">{{.DurationMs}}</td><td><pre>{{if .Type}}{{.Type}}: {{end}}{{.Message}}</pre>
+{{- if .StackTrace}}<details><summary>Stack trace</summary><pre>{{.StackTrace}}</pre></details>{{end}}
+{{- if .SystemOut}}<details><summary>Output</summary><pre>{{.SystemOut}}</pre></details>{{end}}
+{{- if .SystemErr}}<details><summary>Error output</summary><pre>{{.SystemErr}}</pre></details>{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{if .Packages}}
+<h2>Coverage by package</h2>
+<table>
+<tr><th>Package</th><th>Instruction</th><th>Branch</th><th>Line</th><th>Method</th></tr>
+{{range .Packages}}<tr><td>{{.Name}}</td><td class="num">{{.Instruction}}</td><td class="num">{{.Branch}}</td><td class="num">{{.Line}}</td><td class="num">{{.Method}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{range .Comparisons}}
+<h2>Comparison with {{.Baseline.Label}}</h2>
+<table>
+<tr><th>Result Type</th><th>Current</th><th>Previous</th><th>Difference</th><th>Percentage Difference</th></tr>
+{{range .Diffs}}<tr><td>{{.FieldName}}</td><td class="num">{{fixed .CurrentBuildValue}}</td><td class="num">{{fixed .PreviousBuildValue}}</td><td class="num {{.Change}}">{{signed .Difference}}</td><td class="num {{.Change}}">{{fixed .PercentageDifference}}%</td></tr>
+{{end}}</table>
+{{end}}
+
+{{if .Gates}}
+<h2>Quality gates</h2>
+<table>
+<tr><th>Gate</th><th>Value</th><th>Result</th></tr>
+{{range .Gates}}<tr><td>{{.Gate}}</td><td class="num">{{if .Found}}{{fixed .Value}}{{else}}-{{end}}</td><td class="{{if .Passed}}passed{{else}}failed{{end}}">{{if .Passed}}passed{{else}}failed{{end}}{{with .Message}} ({{.}}){{end}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{with .Trend}}
+<h2>Trend over the last {{len .Builds}} builds</h2>
+<table>
+<tr><th>Result Type</th><th>Min</th><th>Max</th><th>Mean</th><th>Slope</th><th>Trend</th></tr>
+{{range .Fields}}<tr><td>{{.Field}}</td><td class="num">{{fixed .Min}}</td><td class="num">{{fixed .Max}}</td><td class="num">{{fixed .Mean}}</td><td class="num">{{signed .Slope}}</td>
+<td><svg width="240" height="60" viewBox="0 0 240 60" role="img" aria-label="{{.Field}} trend"><polyline points="{{chartPoints .Values}}"/>{{with lastPoint .Values}}{{$xy := split . ","}}<circle cx="{{index $xy 0}}" cy="{{index $xy 1}}" r="3"/>{{end}}</svg></td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHtmlReport renders the report as a single HTML file without external
+// assets, so it can be uploaded and opened as a build artifact.
+func WriteHtmlReport(path string, report BuildReport) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML report: %w", err)
+	}
+	defer file.Close()
+
+	return RenderHtmlReport(file, report)
+}
+
+func RenderHtmlReport(w io.Writer, report BuildReport) error {
+	data := htmlReportData{BuildReport: report}
+
+	var fieldNames []string
+	for name := range report.Result.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	for _, name := range fieldNames {
+		value := fmt.Sprintf("%v", report.Result.Fields[name])
+		if number, ok := toFloat64(report.Result.Fields[name]); ok {
+			value = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		data.Fields = append(data.Fields, htmlReportField{Name: name, Value: value})
+	}
+
+	for _, pkg := range report.Result.Packages {
+		data.Packages = append(data.Packages, htmlReportPackage{
+			Name:        pkg.Name,
+			Instruction: packageCoverageStr(pkg, "INSTRUCTION"),
+			Branch:      packageCoverageStr(pkg, "BRANCH"),
+			Line:        packageCoverageStr(pkg, "LINE"),
+			Method:      packageCoverageStr(pkg, "METHOD"),
+		})
+	}
+
+	return htmlReportTemplate.Execute(w, data)
+}
+
+func packageCoverageStr(pkg Package, counterType string) string {
+	coverage, ok := pkg.CounterCoverage(counterType)
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", coverage)
+}
+
+// TrendChartPoints scales values into the SVG polyline points of a trend
+// chart, oldest build on the left.
+func TrendChartPoints(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+
+	width := float64(trendChartWidth - 2*trendChartPadding)
+	height := float64(trendChartHeight - 2*trendChartPadding)
+
+	var points []string
+	for i, value := range values {
+		x := float64(trendChartPadding)
+		if len(values) > 1 {
+			x += width * float64(i) / float64(len(values)-1)
+		}
+		y := float64(trendChartPadding) + height/2
+		if maxValue > minValue {
+			y = float64(trendChartPadding) + height*(maxValue-value)/(maxValue-minValue)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " ")
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHtmlReport(t *testing.T) {
+	report := BuildReport{
+		Tool:       JunitTool,
+		PipelineId: mockPipelineId,
+		BuildId:    mockBuildNumber,
+		Result: AggregateResult{
+			Tool:   JunitTool,
+			Fields: map[string]interface{}{"total_tests": 12, "failed_tests": 1},
+			Failures: []TestFailure{
+				{ClassName: "com.example.LoginTest", Name: "testLogin", Status: "failed", Message: "expected <true>"},
+			},
+			Packages: []Package{
+				{Name: "com/example", Counters: []Counter{{Type: "LINE", Covered: 3, Missed: 1}}},
+			},
+		},
+		Comparisons: []BaselineComparison{{
+			Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 200},
+			Diffs:    ComputeResultDiffs(map[string]float64{"total_tests": 12}, map[string]float64{"total_tests": 10}),
+		}},
+		Trend: &TrendReport{Fields: []FieldTrend{{Field: "total_tests", Min: 10, Max: 12, Mean: 11, Slope: 2, Values: []float64{10, 12}}}},
+	}
+
+	var sb strings.Builder
+	if err := RenderHtmlReport(&sb, report); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	html := sb.String()
+
+	expectedParts := []string{
+		"<td>total_tests</td><td class=\"num\">12</td>",
+		"<td>testLogin</td>",
+		"expected &lt;true&gt;",
+		"<td>com/example</td>",
+		"75.00%",
+		"Comparison with previous (build 200)",
+		"&#43;2.00",
+		"<polyline points=\"4.0,56.0 236.0,4.0\"/>",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(html, part) {
+			t.Errorf("Expected HTML report to contain %q", part)
+		}
+	}
+	if strings.Contains(html, "<script") || strings.Contains(html, "<link") {
+		t.Errorf("Expected a self-contained HTML report without external assets")
+	}
+}
+
+func TestCalculateJacocoAggregateMergesPackages(t *testing.T) {
+	reports := []Report{
+		{Packages: []Package{{Name: "com/a", Counters: []Counter{{Type: "LINE", Covered: 1, Missed: 1}}}}},
+		{Packages: []Package{
+			{Name: "com/a", Counters: []Counter{{Type: "LINE", Covered: 2, Missed: 0}}},
+			{Name: "com/b", Counters: []Counter{{Type: "BRANCH", Covered: 1, Missed: 3}}},
+		}},
+	}
+
+	aggregate := CalculateJacocoAggregate(reports)
+	if len(aggregate.Packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %+v", aggregate.Packages)
+	}
+	if coverage, ok := aggregate.Packages[0].CounterCoverage("LINE"); !ok || coverage != 75 {
+		t.Errorf("Expected 75%% line coverage for com/a, got %v", coverage)
+	}
+	if _, ok := aggregate.Packages[1].CounterCoverage("LINE"); ok {
+		t.Errorf("Expected no line counter for com/b")
+	}
+}
@@ -0,0 +1,220 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/sirupsen/logrus"
+)
+
+// InfluxDb1Store is a ResultStore for InfluxDB 1.x. Points are written with
+// the /write endpoint and read back with InfluxQL through /query, using
+// database, retention policy and username/password authentication.
+type InfluxDb1Store struct {
+	DbCredentials
+	client *http.Client
+}
+
+type influxQLResponse struct {
+	Results []struct {
+		Series []struct {
+			Name    string          `json:"name"`
+			Columns []string        `json:"columns"`
+			Values  [][]interface{} `json:"values"`
+		} `json:"series"`
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+func NewInfluxDb1Store(credentials DbCredentials) *InfluxDb1Store {
+	return &InfluxDb1Store{
+		DbCredentials: credentials,
+		client:        &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *InfluxDb1Store) WritePoint(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}) error {
+	return s.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (s *InfluxDb1Store) WritePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	point := influxdb2.NewPoint(measurement, nonEmptyTags(tags), fields, pointTime)
+	body := write.PointToLineProtocol(point, time.Nanosecond)
+
+	params := url.Values{}
+	params.Set("db", s.Database)
+	params.Set("precision", "ns")
+	if s.RetentionPolicy != "" {
+		params.Set("rp", s.RetentionPolicy)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint("write", params), bytes.NewBufferString(body))
+	if err != nil {
+		return fmt.Errorf("failed to create InfluxDB write request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write point to InfluxDB: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *InfluxDb1Store) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	influxQL := fmt.Sprintf(`SELECT * FROM %s WHERE "pipelineId" = %s AND "group" = %s AND "buildId" = %s AND time > now() - 365d`,
+		quoteIdentifier(query.Measurement), quoteLiteral(query.PipelineId), quoteLiteral(query.Group), quoteLiteral(buildId))
+
+	records, err := s.query(ctx, influxQL)
+	if err != nil {
+		logrus.Println("FetchBuild Error querying InfluxDB: ", err)
+		return nil, err
+	}
+	return fieldsForBuild(records, buildId), nil
+}
+
+func (s *InfluxDb1Store) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	influxQL := fmt.Sprintf(`SELECT * FROM %s WHERE "pipelineId" = %s AND "group" = %s AND time > now() - 365d`,
+		quoteIdentifier(query.Measurement), quoteLiteral(query.PipelineId), quoteLiteral(query.Group))
+
+	records, err := s.query(ctx, influxQL)
+	if err != nil {
+		logrus.Println("ListBuilds Error querying InfluxDB: ", err)
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *InfluxDb1Store) Close() {}
+
+func (s *InfluxDb1Store) query(ctx context.Context, influxQL string) ([]BuildRecord, error) {
+	params := url.Values{}
+	params.Set("db", s.Database)
+	params.Set("q", influxQL)
+	params.Set("epoch", "ns")
+	if s.RetentionPolicy != "" {
+		params.Set("rp", s.RetentionPolicy)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint("query", params), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create InfluxDB query request: %w", err)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query InfluxDB: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response influxQLResponse
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode InfluxDB query response: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("failed to query InfluxDB: %s", response.Error)
+	}
+
+	var records []BuildRecord
+	for _, result := range response.Results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to query InfluxDB: %s", result.Error)
+		}
+		for _, series := range result.Series {
+			for _, row := range series.Values {
+				records = append(records, buildRecordFromRow(series.Columns, row))
+			}
+		}
+	}
+	return records, nil
+}
+
+func (s *InfluxDb1Store) endpoint(path string, params url.Values) string {
+	return strings.TrimSuffix(s.InfluxDBURL, "/") + "/" + path + "?" + params.Encode()
+}
+
+func (s *InfluxDb1Store) do(req *http.Request) (*http.Response, error) {
+	if s.Username != "" || s.Password != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(respBody))}
+	}
+	return resp, nil
+}
+
+// httpStatusError is returned for a non-2xx InfluxDB 1.x response, so callers
+// can tell rejected requests apart from an unavailable server.
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("influxdb returned %s: %s", e.Status, e.Body)
+}
+
+// buildRecordFromRow converts an InfluxQL "SELECT *" row, where tags come back
+// as string columns and fields as numbers, into a BuildRecord.
+func buildRecordFromRow(columns []string, row []interface{}) BuildRecord {
+	record := BuildRecord{
+		Tags:   map[string]string{},
+		Fields: map[string]float64{},
+	}
+	for i, column := range columns {
+		if i >= len(row) || row[i] == nil {
+			continue
+		}
+		if column == "time" {
+			if number, ok := row[i].(json.Number); ok {
+				if nanos, err := number.Int64(); err == nil {
+					record.Time = time.Unix(0, nanos)
+				}
+			}
+			continue
+		}
+		switch value := row[i].(type) {
+		case string:
+			record.Tags[column] = value
+		case json.Number:
+			if field, err := value.Float64(); err == nil {
+				record.Fields[column] = field
+			}
+		case bool:
+			record.Fields[column], _ = toFloat64(value)
+		}
+	}
+	record.BuildId = record.Tags["buildId"]
+	return record
+}
+
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(identifier) + `"`
+}
+
+func quoteLiteral(literal string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(literal) + `'`
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInfluxDb1StoreWritePoint(t *testing.T) {
+	var gotQuery, gotBody, gotUser, gotPassword string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write" {
+			t.Errorf("Expected /write, got %s", r.URL.Path)
+		}
+		gotQuery = r.URL.RawQuery
+		gotUser, gotPassword, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := NewInfluxDb1Store(DbCredentials{InfluxDBURL: server.URL, Database: "ci", RetentionPolicy: "autogen",
+		Username: "admin", Password: "secret"})
+	err := store.WritePoint(context.Background(), JunitTool,
+		map[string]string{"pipelineId": "p1", "buildId": "5", "group": "suite_01"},
+		map[string]interface{}{"total_tests": 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, expected := range []string{"db=ci", "rp=autogen", "precision=ns"} {
+		if !strings.Contains(gotQuery, expected) {
+			t.Errorf("Expected %q in query %q", expected, gotQuery)
+		}
+	}
+	if gotUser != "admin" || gotPassword != "secret" {
+		t.Errorf("Expected basic auth admin/secret, got %s/%s", gotUser, gotPassword)
+	}
+	if !strings.HasPrefix(gotBody, "junit,buildId=5,group=suite_01,pipelineId=p1 total_tests=10i ") {
+		t.Errorf("Unexpected line protocol body: %q", gotBody)
+	}
+}
+
+func TestInfluxDb1StoreListBuilds(t *testing.T) {
+	var gotInfluxQL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotInfluxQL = r.URL.Query().Get("q")
+		_, _ = w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"junit",
+			"columns":["time","buildId","failed_tests","group","pipelineId","total_tests"],
+			"values":[[1738680968448000000,"53",1,"suite_01","p1",9],[1738680999448000000,"54",2,"suite_01","p1",10]]}]}]}`))
+	}))
+	defer server.Close()
+
+	store := NewInfluxDb1Store(DbCredentials{InfluxDBURL: server.URL, Database: "ci"})
+	query := BuildQuery{Measurement: JunitTool, PipelineId: "p1", Group: "suite_01"}
+	records, err := store.ListBuilds(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(gotInfluxQL, `FROM "junit" WHERE "pipelineId" = 'p1' AND "group" = 'suite_01'`) {
+		t.Errorf("Unexpected InfluxQL: %s", gotInfluxQL)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[1].BuildId != "54" || records[1].Fields["total_tests"] != 10 || records[1].Tags["group"] != "suite_01" {
+		t.Errorf("Unexpected record: %+v", records[1])
+	}
+	if records[0].Time.UnixNano() != 1738680968448000000 {
+		t.Errorf("Unexpected record time: %v", records[0].Time)
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	if got := quoteLiteral(`it's`); got != `'it\'s'` {
+		t.Errorf("Expected escaped literal, got %s", got)
+	}
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// InfluxDbStore is a ResultStore backed by an InfluxDB v2 bucket.
+type InfluxDbStore struct {
+	DbCredentials
+	client influxdb2.Client
+}
+
+func NewInfluxDbStore(credentials DbCredentials) *InfluxDbStore {
+	return &InfluxDbStore{
+		DbCredentials: credentials,
+		client:        influxdb2.NewClient(credentials.InfluxDBURL, credentials.InfluxDBToken),
+	}
+}
+
+func (s *InfluxDbStore) WritePoint(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}) error {
+	return s.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (s *InfluxDbStore) WritePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	writeAPI := s.client.WriteAPIBlocking(s.Organization, s.Bucket)
+	point := influxdb2.NewPoint(measurement, tags, fields, pointTime)
+	err := writeAPI.WritePoint(ctx, point)
+	if err != nil {
+		return fmt.Errorf("failed to write point to InfluxDB: %w", err)
+	}
+	return nil
+}
+
+func (s *InfluxDbStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	fluxQuery := fmt.Sprintf(`
+	from(bucket: %s)
+	  |> range(start: -1y)
+	  |> filter(fn: (r) => r._measurement == %s)
+	  |> filter(fn: (r) => r.pipelineId == %s)
+	  |> filter(fn: (r) => r.group == %s)
+	  |> filter(fn: (r) => r.buildId == %s)
+	  |> keep(columns: ["_field", "_value"])
+	`, quoteFluxString(s.Bucket), quoteFluxString(query.Measurement), quoteFluxString(query.PipelineId),
+		quoteFluxString(query.Group), quoteFluxString(buildId))
+
+	result, err := s.client.QueryAPI(s.Organization).Query(ctx, fluxQuery)
+	if err != nil {
+		logrus.Println("FetchBuild Error querying InfluxDB: ", err)
+		return nil, fmt.Errorf("failed to query InfluxDB: %w", err)
+	}
+	defer result.Close()
+
+	fieldValues := make(map[string]float64)
+	for result.Next() {
+		fieldName := result.Record().ValueByKey("_field")
+		value := result.Record().ValueByKey("_value")
+		if fieldName == nil || value == nil {
+			continue
+		}
+
+		valueFloat, ok := toFloat64(value)
+		if !ok {
+			continue
+		}
+		fieldValues[fmt.Sprintf("%v", fieldName)] = valueFloat
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to read InfluxDB query result: %w", result.Err())
+	}
+
+	return fieldValues, nil
+}
+
+func (s *InfluxDbStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	fluxQuery := fmt.Sprintf(`
+	from(bucket: %s)
+	  |> range(start: -1y)
+	  |> filter(fn: (r) => r._measurement == %s and r.pipelineId == %s and r.group == %s)
+	  |> pivot(rowKey:["_time"], columnKey: ["_field"], valueColumn: "_value")
+	  |> group()
+	  |> sort(columns: ["_time"])
+	`, quoteFluxString(s.Bucket), quoteFluxString(query.Measurement), quoteFluxString(query.PipelineId),
+		quoteFluxString(query.Group))
+
+	result, err := s.client.QueryAPI(s.Organization).Query(ctx, fluxQuery)
+	if err != nil {
+		logrus.Println("ListBuilds Error querying InfluxDB: ", err)
+		return nil, fmt.Errorf("failed to query InfluxDB: %w", err)
+	}
+	defer result.Close()
+
+	var records []BuildRecord
+	for result.Next() {
+		records = append(records, buildRecordFromValues(result.Record().Time(), result.Record().Values()))
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to read InfluxDB query result: %w", result.Err())
+	}
+
+	return records, nil
+}
+
+func (s *InfluxDbStore) Close() {
+	s.client.Close()
+}
+
+// quoteFluxString quotes a Flux string literal, escaping interpolation too.
+func quoteFluxString(literal string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `${`, `\${`).Replace(literal) + `"`
+}
+
+// buildRecordFromValues splits a pivoted Flux row into tags (string columns)
+// and fields (numeric columns), dropping the internal "_" and table columns.
+func buildRecordFromValues(recordTime time.Time, values map[string]interface{}) BuildRecord {
+	record := BuildRecord{
+		Time:   recordTime,
+		Tags:   map[string]string{},
+		Fields: map[string]float64{},
+	}
+	for key, value := range values {
+		if strings.HasPrefix(key, "_") || key == "result" || key == "table" || value == nil {
+			continue
+		}
+		if tag, ok := value.(string); ok {
+			record.Tags[key] = tag
+			continue
+		}
+		if field, ok := toFloat64(value); ok {
+			record.Fields[key] = field
+		}
+	}
+	record.BuildId = record.Tags["buildId"]
+	return record
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInfluxDbStoreQuotesFluxStrings(t *testing.T) {
+	var gotQueries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotQueries = append(gotQueries, body.Query)
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := NewInfluxDbStore(DbCredentials{InfluxDBURL: server.URL, InfluxDBToken: "token", Organization: "org", Bucket: "ci"})
+	defer store.Close()
+	query := BuildQuery{Measurement: JunitTool, PipelineId: "p1", Group: `suite" or r.group != "`}
+	if _, err := store.ListBuilds(context.Background(), query); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := store.FetchBuild(context.Background(), query, `5${x}`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(gotQueries) != 2 {
+		t.Fatalf("Expected 2 queries, got %v", gotQueries)
+	}
+	for _, fluxQuery := range gotQueries {
+		if !strings.Contains(fluxQuery, `r.group == "suite\" or r.group != \""`) {
+			t.Errorf("Expected the group to be escaped, got %s", fluxQuery)
+		}
+	}
+	if !strings.Contains(gotQueries[1], `r.buildId == "5\${x}"`) {
+		t.Errorf("Expected the build id interpolation to be escaped, got %s", gotQueries[1])
+	}
+}
+
+func TestQuoteFluxString(t *testing.T) {
+	tests := map[string]string{
+		"suite_01":  `"suite_01"`,
+		`a"b`:       `"a\"b"`,
+		`a\b`:       `"a\\b"`,
+		"cost ${x}": `"cost \${x}"`,
+	}
+	for literal, expected := range tests {
+		if quoted := quoteFluxString(literal); quoted != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, literal, quoted)
+		}
+	}
+}
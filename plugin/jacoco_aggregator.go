@@ -8,10 +8,10 @@ import (
 )
 
 type JacocoAggregator struct {
-	ReportsDir  string
-	ReportsName string
-	Includes    string
-	DbCredentials
+	ReportsDir   string
+	ReportsName  string
+	Includes     string
+	ParseOptions ParseOptions
 }
 
 type JacocoAggregateData struct {
@@ -43,71 +43,142 @@ type Report struct {
 	JacocoAggregateData
 }
 
+// UnmarshalXML reads the report one token at a time and keeps only the
+// report and package counters. Groups, classes, source files and lines are
+// skipped without being held in memory, so large reports parse in bounded
+// memory.
+func (r *Report) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Local != "report" {
+		return fmt.Errorf("expected element type <report> but have <%s>", start.Name.Local)
+	}
+	r.XMLName = start.Name
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "counter":
+				var counter Counter
+				if err := d.DecodeElement(&counter, &element); err != nil {
+					return err
+				}
+				r.Counters = append(r.Counters, counter)
+			case "package":
+				pkg, err := decodePackage(d, element)
+				if err != nil {
+					return err
+				}
+				r.Packages = append(r.Packages, pkg)
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func decodePackage(d *xml.Decoder, start xml.StartElement) (Package, error) {
+	pkg := Package{}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "name" {
+			pkg.Name = attr.Value
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return pkg, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Local != "counter" {
+				if err := d.Skip(); err != nil {
+					return pkg, err
+				}
+				continue
+			}
+			var counter Counter
+			if err := d.DecodeElement(&counter, &element); err != nil {
+				return pkg, err
+			}
+			pkg.Counters = append(pkg.Counters, counter)
+		case xml.EndElement:
+			return pkg, nil
+		}
+	}
+}
+
 type Counter struct {
-	Type    string `xml:"type,attr"`
-	Missed  int    `xml:"missed,attr"`
-	Covered int    `xml:"covered,attr"`
+	Type    string `xml:"type,attr" json:"type"`
+	Missed  int    `xml:"missed,attr" json:"missed"`
+	Covered int    `xml:"covered,attr" json:"covered"`
+}
+
+// jacocoCoverageTypes are the counter types reported as coverage, with
+// their labels in the reports.
+var jacocoCoverageTypes = []struct {
+	Label string
+	Field string
+}{
+	{"Instruction", "instruction"},
+	{"Branch", "branch"},
+	{"Line", "line"},
+	{"Complexity", "complexity"},
+	{"Method", "method"},
+	{"Class", "class"},
 }
 
 type Package struct {
-	Name     string    `xml:"name,attr"`
-	Counters []Counter `xml:"counter"`
+	Name     string    `xml:"name,attr" json:"name"`
+	Counters []Counter `xml:"counter" json:"counters"`
 }
 
-func GetNewJacocoAggregator(reportsDir, reportsName, includes,
-	dbUrl, dbToken, organization, bucket string) JacocoAggregator {
+func GetNewJacocoAggregator(reportsDir, reportsName, includes string, options ParseOptions) JacocoAggregator {
 	return JacocoAggregator{
-		ReportsDir:  reportsDir,
-		ReportsName: reportsName,
-		Includes:    includes,
-		DbCredentials: DbCredentials{
-			InfluxDBURL:   dbUrl,
-			InfluxDBToken: dbToken,
-			Organization:  organization,
-			Bucket:        bucket,
-		},
+		ReportsDir:   reportsDir,
+		ReportsName:  reportsName,
+		Includes:     includes,
+		ParseOptions: options,
 	}
 }
 
-func (j *JacocoAggregator) Aggregate(groupName string) error {
+func (j *JacocoAggregator) Aggregate() (AggregateResult, error) {
 
 	logrus.Println("Jacoco Aggregator Aggregate")
-	tagsMap, fieldsMap, err := Aggregate[Report](j.ReportsDir, j.Includes,
-		j.DbCredentials.InfluxDBURL, j.DbCredentials.InfluxDBToken,
-		j.DbCredentials.Organization, j.DbCredentials.Bucket, JacocoTool, groupName,
+	aggregate, result, err := Aggregate[Report](j.ReportsDir, j.Includes, j.ParseOptions,
 		CalculateJacocoAggregate, GetJacocoDataMaps, ShowJacocoStats)
+	result.Tool, result.Packages = JacocoTool, aggregate.Packages
+	if err != nil {
+		logrus.Errorf("Error aggregating Jacoco results: %v", err)
+		return result, err
+	}
 
-	err = ExportJacocoOutputVars(tagsMap, fieldsMap)
+	err = ExportJacocoOutputVars(result.Tags, result.Fields)
 	if err != nil {
 		logrus.Errorf("Error exporting Jacoco coverage metrics: %v", err)
-		return err
+		return result, err
 	}
 
-	return nil
+	return result, nil
 }
 
 func ExportJacocoOutputVars(tagsMap map[string]string, fieldsMap map[string]interface{}) error {
 
-	instructionCoveragePercentage := CalculatePercentage(int(fieldsMap["instruction_covered_sum"].(float64)),
-		int(fieldsMap["instruction_missed_sum"].(float64)))
-	branchCoveragePercentage := CalculatePercentage(int(fieldsMap["branch_covered_sum"].(float64)),
-		int(fieldsMap["branch_missed_sum"].(float64)))
-	lineCoveragePercentage := CalculatePercentage(int(fieldsMap["line_covered_sum"].(float64)),
-		int(fieldsMap["line_missed_sum"].(float64)))
-	complexityCoverage := CalculatePercentage(int(fieldsMap["complexity_covered_sum"].(float64)),
-		int(fieldsMap["complexity_missed_sum"].(float64)))
-	methodCoveragePercentage := CalculatePercentage(int(fieldsMap["method_covered_sum"].(float64)),
-		int(fieldsMap["method_missed_sum"].(float64)))
-	classCoveragePercentage := CalculatePercentage(int(fieldsMap["class_covered_sum"].(float64)),
-		int(fieldsMap["class_missed_sum"].(float64)))
-
 	outputVarsMap := map[string]interface{}{
-		"INSTRUCTION_COVERAGE": instructionCoveragePercentage,
-		"BRANCH_COVERAGE":      branchCoveragePercentage,
-		"LINE_COVERAGE":        lineCoveragePercentage,
-		"COMPLEXITY_COVERAGE":  complexityCoverage,
-		"METHOD_COVERAGE":      methodCoveragePercentage,
-		"CLASS_COVERAGE":       classCoveragePercentage,
+		"INSTRUCTION_COVERAGE": fieldsMap["instruction"+CoverageFieldSuffix],
+		"BRANCH_COVERAGE":      fieldsMap["branch"+CoverageFieldSuffix],
+		"LINE_COVERAGE":        fieldsMap["line"+CoverageFieldSuffix],
+		"COMPLEXITY_COVERAGE":  fieldsMap["complexity"+CoverageFieldSuffix],
+		"METHOD_COVERAGE":      fieldsMap["method"+CoverageFieldSuffix],
+		"CLASS_COVERAGE":       fieldsMap["class"+CoverageFieldSuffix],
 	}
 
 	for key, value := range outputVarsMap {
@@ -123,8 +194,20 @@ func ExportJacocoOutputVars(tagsMap map[string]string, fieldsMap map[string]inte
 func CalculateJacocoAggregate(reportsList []Report) Report {
 
 	var xmlFileReportData Report
+	packageIndex := map[string]int{}
 
 	for _, report := range reportsList {
+		for _, pkg := range report.Packages {
+			index, exists := packageIndex[pkg.Name]
+			if !exists {
+				index = len(xmlFileReportData.Packages)
+				packageIndex[pkg.Name] = index
+				xmlFileReportData.Packages = append(xmlFileReportData.Packages, Package{Name: pkg.Name})
+			}
+			xmlFileReportData.Packages[index].Counters = mergeCounters(xmlFileReportData.Packages[index].Counters, pkg.Counters)
+		}
+		// the merged counters are kept so aggregates can be aggregated again
+		xmlFileReportData.Counters = mergeCounters(xmlFileReportData.Counters, report.Counters)
 		for _, counter := range report.Counters {
 			switch counter.Type {
 			case "INSTRUCTION":
@@ -185,10 +268,42 @@ func GetJacocoDataMaps(pipelineId, buildNumber string,
 		"class_covered_sum":       aggregateData.ClassCoveredSum,
 		"class_missed_sum":        aggregateData.ClassMissedSum,
 	}
+	AddDerivedFields(fieldMap)
 
 	return tagMap, fieldMap
 }
 
+// mergeCounters adds the covered and missed counts of counters to the
+// matching types in merged.
+func mergeCounters(merged []Counter, counters []Counter) []Counter {
+	for _, counter := range counters {
+		found := false
+		for i := range merged {
+			if merged[i].Type == counter.Type {
+				merged[i].Covered += counter.Covered
+				merged[i].Missed += counter.Missed
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, counter)
+		}
+	}
+	return merged
+}
+
+// CounterCoverage returns the coverage percentage of the counter type, for
+// example "LINE", and whether the counter exists.
+func (p Package) CounterCoverage(counterType string) (float64, bool) {
+	for _, counter := range p.Counters {
+		if counter.Type == counterType {
+			return CalculatePercentage(counter.Covered, counter.Missed), true
+		}
+	}
+	return 0, false
+}
+
 func addToSum(totalSum *float64, coveredSum *float64, missedSum *float64,
 	covered float64, missed float64) {
 	*totalSum += covered + missed
@@ -205,8 +320,8 @@ func CalculatePercentage(covered, missed int) float64 {
 }
 
 func ShowJacocoStats(tags map[string]string, fields map[string]interface{}) error {
-	border := "==================================================================="
-	separator := "-------------------------------------------------------------------"
+	border := "================================================================================"
+	separator := "--------------------------------------------------------------------------------"
 
 	table := []string{
 		border,
@@ -215,20 +330,20 @@ func ShowJacocoStats(tags map[string]string, fields map[string]interface{}) erro
 		fmt.Sprintf("  %-20s: %-65s ", "Pipeline ID", tags["pipelineId"]),
 		fmt.Sprintf("  %-20s: %-65s ", "Build ID", tags["buildId"]),
 		border,
-		fmt.Sprintf("| %-25s | %-10s | %-10s | %-10s |", "Coverage Type", "Total", "Covered", "Missed"),
+		fmt.Sprintf("| %-25s | %-10s | %-10s | %-10s | %-10s |", "Coverage Type", "Total", "Covered", "Missed", "Coverage"),
 		separator,
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Instruction Coverage",
-			fields["instruction_total_sum"], fields["instruction_covered_sum"], fields["instruction_missed_sum"]),
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Branch Coverage",
-			fields["branch_total_sum"], fields["branch_covered_sum"], fields["branch_missed_sum"]),
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Line Coverage",
-			fields["line_total_sum"], fields["line_covered_sum"], fields["line_missed_sum"]),
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Complexity Coverage",
-			fields["complexity_total_sum"], fields["complexity_covered_sum"], fields["complexity_missed_sum"]),
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Method Coverage",
-			fields["method_total_sum"], fields["method_covered_sum"], fields["method_missed_sum"]),
-		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f |", "✅ Class Coverage",
-			fields["class_total_sum"], fields["class_covered_sum"], fields["class_missed_sum"]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Instruction Coverage",
+			fields["instruction_total_sum"], fields["instruction_covered_sum"], fields["instruction_missed_sum"], fields["instruction"+CoverageFieldSuffix]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Branch Coverage",
+			fields["branch_total_sum"], fields["branch_covered_sum"], fields["branch_missed_sum"], fields["branch"+CoverageFieldSuffix]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Line Coverage",
+			fields["line_total_sum"], fields["line_covered_sum"], fields["line_missed_sum"], fields["line"+CoverageFieldSuffix]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Complexity Coverage",
+			fields["complexity_total_sum"], fields["complexity_covered_sum"], fields["complexity_missed_sum"], fields["complexity"+CoverageFieldSuffix]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Method Coverage",
+			fields["method_total_sum"], fields["method_covered_sum"], fields["method_missed_sum"], fields["method"+CoverageFieldSuffix]),
+		fmt.Sprintf("| %-25s | %10.2f | %10.2f | %10.2f | %9.2f%% |", "✅ Class Coverage",
+			fields["class_total_sum"], fields["class_covered_sum"], fields["class_missed_sum"], fields["class"+CoverageFieldSuffix]),
 		border,
 	}
 
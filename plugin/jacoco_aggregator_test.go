@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -217,6 +218,24 @@ func TestCalculateJacocoAggregate(t *testing.T) {
 	}
 }
 
+func TestCalculateJacocoAggregateOfAggregates(t *testing.T) {
+	reports := []Report{
+		{Counters: []Counter{{Type: "LINE", Covered: 10, Missed: 5}},
+			Packages: []Package{{Name: "a", Counters: []Counter{{Type: "LINE", Covered: 10, Missed: 5}}}}},
+		{Counters: []Counter{{Type: "LINE", Covered: 6, Missed: 4}, {Type: "BRANCH", Covered: 2, Missed: 2}},
+			Packages: []Package{{Name: "a", Counters: []Counter{{Type: "LINE", Covered: 6, Missed: 4}}}}},
+	}
+	var fileAggregates []Report
+	for _, report := range reports {
+		fileAggregates = append(fileAggregates, CalculateJacocoAggregate([]Report{report}))
+	}
+
+	expected := CalculateJacocoAggregate(reports)
+	if result := CalculateJacocoAggregate(fileAggregates); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected the aggregate of the file aggregates to be %+v, got %+v", expected, result)
+	}
+}
+
 func TestCalculatePercentage(t *testing.T) {
 	tests := []struct {
 		covered, missed int
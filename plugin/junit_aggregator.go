@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/harness-community/parse-test-reports/gojunit"
-	"github.com/mattn/go-zglob"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	"io"
@@ -18,10 +17,10 @@ import (
 )
 
 type JunitAggregator struct {
-	ReportsDir  string
-	ReportsName string
-	Includes    string
-	DbCredentials
+	ReportsDir   string
+	ReportsName  string
+	Includes     string
+	ParseOptions ParseOptions
 }
 
 type TestStats struct {
@@ -30,82 +29,103 @@ type TestStats struct {
 	PassCount    int
 	SkippedCount int
 	ErrorCount   int
+	Failures     []TestFailure
+	Files        []FileResult
+	SkippedFiles []SkippedFile
+	// Executions are the test runs in file order. With a rerun policy, the
+	// counts are those of the deduplicated Tests.
+	Executions  []TestExecution
+	Tests       []TestExecution
+	Suites      []TestSuiteResult
+	RerunPolicy string
+	RerunCount  int
+	FlakyCount  int
 }
 
 func GetNewJunitAggregator(
-	reportsDir, reportsName, includes, dbUrl, dbToken, dbOrg, dbBucket string) *JunitAggregator {
+	reportsDir, reportsName, includes string, options ParseOptions) *JunitAggregator {
 	return &JunitAggregator{
-		ReportsDir:  reportsDir,
-		ReportsName: reportsName,
-		Includes:    includes,
-		DbCredentials: DbCredentials{
-			InfluxDBURL:   dbUrl,
-			InfluxDBToken: dbToken,
-			Organization:  dbOrg,
-			Bucket:        dbBucket,
-		},
+		ReportsDir:   reportsDir,
+		ReportsName:  reportsName,
+		Includes:     includes,
+		ParseOptions: options,
 	}
 }
 
-func (j *JunitAggregator) Aggregate(groupName string) error {
+func (j *JunitAggregator) Aggregate() (AggregateResult, error) {
 	logrus.Println("JunitAggregator Aggregator Aggregate")
+	result := AggregateResult{Tool: JunitTool}
 
 	reportsRootDir := j.ReportsDir
-	includes := j.Includes
-	var xmlReportFiles []string
-	patterns := strings.Split(includes, ",")
+	patterns := SplitPatterns(j.Includes)
 
-	for _, pattern := range patterns {
-		tmpReportDir := os.DirFS(reportsRootDir)
-		relPattern := strings.TrimPrefix(pattern, reportsRootDir+"/")
-
-		filesList, err := doublestar.Glob(tmpReportDir, relPattern)
-		if err != nil {
-			logrus.Println("Include patterns not found ", err.Error())
-			return err
-		}
-		xmlReportFiles = append(xmlReportFiles, filesList...)
+	xmlReportFiles, err := DiscoverReportFiles(reportsRootDir, patterns, SplitPatterns(j.ParseOptions.ExcludePattern))
+	if err != nil {
+		return result, err
 	}
 
 	for i, tmpXmlReportFile := range xmlReportFiles {
 		xmlReportFiles[i] = filepath.Join(reportsRootDir, tmpXmlReportFile)
 	}
 
-	totalAggregate, err := ParseTests(xmlReportFiles, logrus.New())
-	if err != nil {
+	// failed tests are not an error of the aggregation, fail_on_test_failure
+	// decides about them once the results are stored and reported
+	totalAggregate, err := ParseTests(xmlReportFiles, j.ParseOptions, logrus.New())
+	if err != nil && !errors.Is(err, ErrTestsFailed) {
 		logrus.Println("error: ", err)
+		return result, err
+	}
+	for _, file := range totalAggregate.SkippedFiles {
+		if relPath, err := filepath.Rel(reportsRootDir, file.Path); err == nil {
+			file.Path = relPath
+		}
+		result.SkippedFiles = append(result.SkippedFiles, file)
+	}
+	parsed := len(totalAggregate.Files)
+	err = CheckParsedReports(j.ParseOptions, parsed+len(result.SkippedFiles), parsed, result.SkippedFiles)
+	if err != nil {
+		return result, err
 	}
 
 	pipelineId, buildNumber, err := GetPipelineInfo()
 	if err != nil {
 		logrus.Println("Error getting pipeline info: ", err.Error())
-		return err
+		return result, err
 	}
 
 	tagsMap, fieldsMap := GetJunitDataMaps(pipelineId, buildNumber, totalAggregate)
+	result.Tags, result.Fields, result.Failures = tagsMap, fieldsMap, totalAggregate.Failures
+	for _, file := range totalAggregate.Files {
+		if relPath, err := filepath.Rel(reportsRootDir, file.Path); err == nil {
+			file.Path = relPath
+		}
+		result.Files = append(result.Files, file)
+	}
+	for _, test := range totalAggregate.Tests {
+		if relPath, err := filepath.Rel(reportsRootDir, test.File); err == nil {
+			test.File = relPath
+		}
+		result.Tests = append(result.Tests, test)
+	}
+	for _, suite := range totalAggregate.Suites {
+		if relPath, err := filepath.Rel(reportsRootDir, suite.File); err == nil {
+			suite.File = relPath
+		}
+		result.Suites = append(result.Suites, suite)
+	}
 	err = ShowJunitStats(tagsMap, fieldsMap)
 	if err != nil {
 		logrus.Println("Error showing build stats: ", err.Error())
-		return err
+		return result, err
 	}
 
 	err = ExportJunitOutputVars(tagsMap, fieldsMap)
 	if err != nil {
 		logrus.Println("Error exporting Junit output vars: ", err.Error())
-		return err
+		return result, err
 	}
 
-	if j.DbCredentials.InfluxDBURL != "" && j.DbCredentials.InfluxDBToken != "" &&
-		j.DbCredentials.Organization != "" && j.DbCredentials.Bucket != "" {
-		err = PersistToInfluxDb(j.DbCredentials.InfluxDBURL, j.DbCredentials.InfluxDBToken,
-			j.DbCredentials.Organization, j.DbCredentials.Bucket, JunitTool, groupName, tagsMap, fieldsMap)
-		if err != nil {
-			logrus.Println("Error persisting data to InfluxDB: ", err.Error())
-			return err
-		}
-	}
-
-	return err
+	return result, err
 }
 
 func GetJunitDataMaps(pipelineId, buildNumber string, aggregateData TestStats) (map[string]string, map[string]interface{}) {
@@ -121,6 +141,11 @@ func GetJunitDataMaps(pipelineId, buildNumber string, aggregateData TestStats) (
 		"skipped_tests": aggregateData.SkippedCount,
 		"errors_count":  aggregateData.ErrorCount,
 	}
+	if aggregateData.RerunPolicy != "" {
+		fields[FlakyTestsField] = aggregateData.FlakyCount
+		fields[RerunTestsField] = aggregateData.RerunCount
+	}
+	AddDerivedFields(fields)
 
 	return tags, fields
 }
@@ -133,6 +158,9 @@ func ExportJunitOutputVars(tags map[string]string, fields map[string]interface{}
 		"TOTAL_SKIPPED": fields["skipped_tests"],
 		"TOTAL_ERRORS":  fields["errors_count"],
 	}
+	if flaky, ok := fields[FlakyTestsField]; ok {
+		outputVarsMap["TOTAL_FLAKY"] = flaky
+	}
 	for key, value := range outputVarsMap {
 		err := WriteToEnvVariable(key, fmt.Sprintf("%v", value))
 		if err != nil {
@@ -161,38 +189,20 @@ func ShowJunitStats(tags map[string]string, fields map[string]interface{}) error
 		fmt.Sprintf("| ❌ Total Failed     | %10.2f          |", float64(fields["failed_tests"].(int))),
 		fmt.Sprintf("| ⏸️ Total Skipped    | %10.2f          |", float64(fields["skipped_tests"].(int))),
 		fmt.Sprintf("| 🛑 Total Errors     | %10.2f          |", float64(fields["errors_count"].(int))),
-		border,
+		fmt.Sprintf("| 📈 Pass Rate        | %9.2f%%          |", fields[PassRateField]),
 	}
+	if flaky, ok := fields[FlakyTestsField].(int); ok {
+		table = append(table, fmt.Sprintf("| 🔁 Flaky Passed     | %10.2f          |", float64(flaky)))
+	}
+	table = append(table, border)
 
 	fmt.Println(strings.Join(table, "\n"))
 	return nil
 }
 
-func CompareJunitResults(tool string, args Args) (string, error) {
-	var resultStr string
-	currentPipelineId, currentBuildNumber, err := GetPipelineInfo()
-	if err != nil {
-		fmt.Println("CompareResults Error getting pipeline info: ", err)
-		return resultStr, err
-	}
-
-	previousBuildId, err := GetPreviousBuildId(tool, args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket, currentPipelineId, args.GroupName, currentBuildNumber, args)
-	if err != nil {
-		fmt.Println("CompareResults Error getting previous build id: ", err)
-		return resultStr, err
-	}
-
-	resultStr, err = GetComparedDifferences(tool, args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket,
-		currentPipelineId, args.GroupName, currentBuildNumber, strconv.Itoa(previousBuildId))
-	if err != nil {
-		fmt.Println("CompareResults Error getting compared differences: ", err)
-		return resultStr, err
-	}
-	return resultStr, nil
-}
-
-func ParseTests(paths []string, log *logrus.Logger) (TestStats, error) {
-	files := getFiles(paths, log)
+// ParseTests parses the report files with a pool of workers and merges the per file
+// stats in the order of the files.
+func ParseTests(files []string, options ParseOptions, log *logrus.Logger) (TestStats, error) {
 	stats := TestStats{}
 
 	if len(files) == 0 {
@@ -200,28 +210,21 @@ func ParseTests(paths []string, log *logrus.Logger) (TestStats, error) {
 		return stats, nil
 	}
 
-	for _, file := range files {
-		suites, err := gojunit.IngestFile(file)
-		if err != nil {
-			log.WithError(err).WithField("file", file).Errorln("could not parse file")
+	sources, skippedFiles, cleanup := ExpandReportSources("", files, options.ArchivePattern)
+	defer cleanup()
+	stats.SkippedFiles = skippedFiles
+
+	fileStatsList, errs := parseFiles(sources, options.ParseWorkers(), parseTestSource)
+	for i, source := range sources {
+		if errs[i] != nil {
+			log.WithError(errs[i]).WithField("file", source.Path).Errorln("could not parse file")
+			stats.SkippedFiles = append(stats.SkippedFiles, SkippedFile{Path: source.Path, Reason: errs[i].Error()})
 			continue
 		}
-		fileStats := TestStats{}
-		for _, suite := range suites {
-			for _, test := range suite.Tests {
-				fileStats.TestCount++
-				switch test.Result.Status {
-				case "passed":
-					fileStats.PassCount++
-				case "failed":
-					fileStats.FailCount++
-				case "skipped":
-					fileStats.SkippedCount++
-				case "error":
-					fileStats.ErrorCount++
-				}
-			}
-		}
+		fileStats := fileStatsList[i]
+
+		_, fileFields := GetJunitDataMaps("", "", fileStats)
+		stats.Files = append(stats.Files, FileResult{Path: source.Path, Fields: fileFields})
 
 		// Aggregate stats
 		stats.TestCount += fileStats.TestCount
@@ -229,14 +232,68 @@ func ParseTests(paths []string, log *logrus.Logger) (TestStats, error) {
 		stats.FailCount += fileStats.FailCount
 		stats.SkippedCount += fileStats.SkippedCount
 		stats.ErrorCount += fileStats.ErrorCount
+		stats.Failures = append(stats.Failures, fileStats.Failures...)
+		stats.Executions = append(stats.Executions, fileStats.Executions...)
+		stats.Suites = append(stats.Suites, fileStats.Suites...)
+	}
+
+	stats.Tests = stats.Executions
+	if options.RerunPolicy != "" {
+		applyRerunPolicy(&stats, options.RerunPolicy)
+		log.Infof("Deduplicated %d reruns with the %s policy, %d tests passed on retry",
+			stats.RerunCount, options.RerunPolicy, stats.FlakyCount)
 	}
 
 	if stats.FailCount > 0 || stats.ErrorCount > 0 {
-		return stats, errors.New("failed tests and errors found")
+		return stats, fmt.Errorf("%w: %d failed tests and %d errors found", ErrTestsFailed, stats.FailCount, stats.ErrorCount)
 	}
 	return stats, nil
 }
 
+func parseTestSource(source ReportSource) (TestStats, error) {
+	fileStats := TestStats{}
+	reader, err := source.Open()
+	if err != nil {
+		return fileStats, err
+	}
+	defer reader.Close()
+	suites, err := gojunit.IngestReader(reader)
+	if err != nil {
+		return fileStats, err
+	}
+	for _, suite := range suites {
+		fileStats.Suites = append(fileStats.Suites, newTestSuiteResult(suite, source.Path))
+		for _, test := range suite.Tests {
+			fileStats.TestCount++
+			switch test.Result.Status {
+			case "passed":
+				fileStats.PassCount++
+			case "failed":
+				fileStats.FailCount++
+			case "skipped":
+				fileStats.SkippedCount++
+			case "error":
+				fileStats.ErrorCount++
+			}
+			execution := TestExecution{
+				ClassName:  test.Classname,
+				Name:       test.Name,
+				Status:     string(test.Result.Status),
+				DurationMs: test.DurationMs,
+				File:       source.Path,
+			}
+			if isFailedStatus(execution.Status) {
+				execution.Failure = newTestFailure(test)
+				fileStats.Failures = append(fileStats.Failures, *execution.Failure)
+			}
+			fileStats.Executions = append(fileStats.Executions, execution)
+		}
+	}
+	return fileStats, nil
+}
+
+// getFiles resolves absolute or ~ patterns with the same discovery as the
+// include patterns, each pattern from its static base directory.
 func getFiles(paths []string, log *logrus.Logger) []string {
 	var files []string
 	for _, p := range paths {
@@ -245,27 +302,17 @@ func getFiles(paths []string, log *logrus.Logger) []string {
 			log.WithError(err).WithField("path", p).Errorln("error expanding path")
 			continue
 		}
-		matches, err := zglob.Glob(path)
+		base, pattern := doublestar.SplitPattern(filepath.ToSlash(path))
+		matches, err := DiscoverReportFiles(base, []string{pattern}, nil)
 		if err != nil {
 			log.WithError(err).WithField("path", path).Errorln("error resolving path regex")
 			continue
 		}
-
-		files = append(files, matches...)
-	}
-	return uniqueItems(files)
-}
-
-func uniqueItems(items []string) []string {
-	var result []string
-	set := make(map[string]bool)
-	for _, item := range items {
-		if !set[item] {
-			result = append(result, item)
-			set[item] = true
+		for _, match := range matches {
+			files = append(files, filepath.Join(base, match))
 		}
 	}
-	return result
+	return files
 }
 
 func expandTilde(path string) (string, error) {
@@ -345,6 +392,7 @@ func ParseTestsWithQuarantine(paths []string, quarantineList map[string]interfac
 		suites, err := gojunit.IngestFile(file)
 		if err != nil {
 			log.WithError(err).WithField("file", file).Errorln("could not parse file")
+			stats.SkippedFiles = append(stats.SkippedFiles, SkippedFile{Path: file, Reason: err.Error()})
 			continue
 		}
 		fileStats := TestStats{}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harness-community/parse-test-reports/gojunit"
+)
+
+// MaxOutputExcerptLength is the number of bytes kept of a stack trace and of
+// the output of a failed test.
+const MaxOutputExcerptLength = 2000
+
+// TestSuiteResult holds the properties and totals of a JUnit test suite.
+type TestSuiteResult struct {
+	Name       string            `json:"name"`
+	File       string            `json:"file"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Tests      int               `json:"tests"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+// newTestFailure keeps why a test failed: the failure type and message, the
+// start of the stack trace and the end of its output, where the failure is
+// usually logged.
+func newTestFailure(test gojunit.Test) *TestFailure {
+	return &TestFailure{
+		ClassName:  test.Classname,
+		Name:       test.Name,
+		Status:     string(test.Result.Status),
+		Message:    test.Result.Message,
+		Type:       test.Result.Type,
+		StackTrace: excerpt(test.Result.Desc, MaxOutputExcerptLength, false),
+		SystemOut:  excerpt(test.SystemOut, MaxOutputExcerptLength, true),
+		SystemErr:  excerpt(test.SystemErr, MaxOutputExcerptLength, true),
+		DurationMs: test.DurationMs,
+	}
+}
+
+func newTestSuiteResult(suite gojunit.Suite, file string) TestSuiteResult {
+	return TestSuiteResult{
+		Name:       suite.Name,
+		File:       file,
+		Properties: suite.Properties,
+		Tests:      suite.Totals.Tests,
+		DurationMs: suite.Totals.DurationMs,
+	}
+}
+
+// excerpt keeps the first limit bytes of the text, or the last ones with
+// fromEnd, and notes how many bytes were left out.
+func excerpt(text string, limit int, fromEnd bool) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= limit {
+		return text
+	}
+	if fromEnd {
+		start := len(text) - limit
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		return fmt.Sprintf("[%d bytes truncated]\n%s", start, text[start:])
+	}
+	end := limit
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s\n[%d bytes truncated]", text[:end], len(text)-end)
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const detailedJunitXml = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.LoginTest" tests="2" failures="1" errors="0" skipped="0" time="1.5">
+  <properties>
+    <property name="java.version" value="17.0.2"/>
+  </properties>
+  <testcase classname="com.example.LoginTest" name="testLogin" time="0.25"/>
+  <testcase classname="com.example.LoginTest" name="testLogout" time="1.25">
+    <failure message="expected &lt;true&gt;" type="org.opentest4j.AssertionFailedError">org.opentest4j.AssertionFailedError: expected &lt;true&gt;
+	at com.example.LoginTest.testLogout(LoginTest.java:42)</failure>
+    <system-out>logging out user 42</system-out>
+  </testcase>
+</testsuite>`
+
+func TestParseTestsKeepsFailureDetails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "TEST-LoginTest.xml")
+	os.WriteFile(path, []byte(detailedJunitXml), 0644)
+
+	stats, _ := ParseTests([]string{path}, ParseOptions{}, logrus.New())
+	if len(stats.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %+v", stats.Failures)
+	}
+	failure := stats.Failures[0]
+	if failure.Type != "org.opentest4j.AssertionFailedError" || failure.Message != "expected <true>" {
+		t.Errorf("Unexpected failure type and message: %+v", failure)
+	}
+	if !strings.Contains(failure.StackTrace, "LoginTest.java:42") || failure.SystemOut != "logging out user 42" {
+		t.Errorf("Expected the stack trace and output, got %+v", failure)
+	}
+	if failure.DurationMs != 1250 {
+		t.Errorf("Expected a duration of 1250 ms, got %d", failure.DurationMs)
+	}
+
+	if len(stats.Tests) != 2 || stats.Tests[0].DurationMs != 250 || stats.Tests[0].File != path {
+		t.Errorf("Unexpected tests: %+v", stats.Tests)
+	}
+	if len(stats.Suites) != 1 || stats.Suites[0].Properties["java.version"] != "17.0.2" || stats.Suites[0].Tests != 2 {
+		t.Errorf("Unexpected suites: %+v", stats.Suites)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if excerpt("  short  ", 10, false) != "short" {
+		t.Errorf("Expected short text to be kept")
+	}
+	if head := excerpt("0123456789", 4, false); head != "0123\n[6 bytes truncated]" {
+		t.Errorf("Unexpected head excerpt %q", head)
+	}
+	if tail := excerpt("0123456789", 4, true); tail != "[6 bytes truncated]\n6789" {
+		t.Errorf("Unexpected tail excerpt %q", tail)
+	}
+	if head := excerpt("aé", 2, false); head != "a\n[2 bytes truncated]" {
+		t.Errorf("Expected the excerpt to end at a rune boundary, got %q", head)
+	}
+}
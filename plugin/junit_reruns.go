@@ -0,0 +1,123 @@
+package plugin
+
+import "fmt"
+
+const (
+	// LastRerunPolicy keeps the result of the last execution of a test.
+	LastRerunPolicy = "last"
+	// AnyPassRerunPolicy passes a test that passed in any execution.
+	AnyPassRerunPolicy = "any-pass"
+	// AnyFailRerunPolicy fails a test that failed in any execution.
+	AnyFailRerunPolicy = "any-fail"
+
+	FlakyTestsField = "flaky_tests"
+	RerunTestsField = "rerun_tests"
+)
+
+// TestExecution is one run of a test in a report file. Failure holds the
+// details of a failed or errored run.
+type TestExecution struct {
+	ClassName  string       `json:"class_name"`
+	Name       string       `json:"name"`
+	Status     string       `json:"status"`
+	DurationMs int64        `json:"duration_ms"`
+	File       string       `json:"file"`
+	Flaky      bool         `json:"flaky,omitempty"`
+	Failure    *TestFailure `json:"-"`
+}
+
+func validateRerunPolicy(policy string) error {
+	switch policy {
+	case "", LastRerunPolicy, AnyPassRerunPolicy, AnyFailRerunPolicy:
+		return nil
+	}
+	return fmt.Errorf("rerun policy %s not supported, use %s, %s or %s",
+		policy, LastRerunPolicy, AnyPassRerunPolicy, AnyFailRerunPolicy)
+}
+
+func isFailedStatus(status string) bool {
+	return status == "failed" || status == "error"
+}
+
+// DeduplicateReruns merges the executions of each test, identified by class
+// and name, into one result chosen by the policy. The results keep the order
+// in which the tests were first run. A test is flaky when it passed in an
+// execution after a failed one, whatever the policy.
+func DeduplicateReruns(executions []TestExecution, policy string) ([]TestExecution, int) {
+	type testRuns struct {
+		result     TestExecution
+		failed     bool
+		passed     bool
+		flaky      bool
+		lastFailed TestExecution
+	}
+
+	index := map[[2]string]int{}
+	var tests []testRuns
+	for _, execution := range executions {
+		key := [2]string{execution.ClassName, execution.Name}
+		i, exists := index[key]
+		if !exists {
+			i = len(tests)
+			index[key] = i
+			tests = append(tests, testRuns{})
+		}
+		test := &tests[i]
+		if execution.Status == "passed" && test.failed {
+			test.flaky = true
+		}
+		if isFailedStatus(execution.Status) {
+			test.failed, test.lastFailed = true, execution
+		}
+		test.passed = test.passed || execution.Status == "passed"
+
+		switch {
+		case policy == AnyPassRerunPolicy && test.passed:
+			if execution.Status == "passed" {
+				test.result = execution
+			}
+		case policy == AnyFailRerunPolicy && test.failed:
+			test.result = test.lastFailed
+		default:
+			test.result = execution
+		}
+	}
+
+	results := make([]TestExecution, 0, len(tests))
+	flaky := 0
+	for _, test := range tests {
+		test.result.Flaky = test.flaky
+		results = append(results, test.result)
+		if test.flaky {
+			flaky++
+		}
+	}
+	return results, flaky
+}
+
+// applyRerunPolicy recounts the stats from the deduplicated results.
+func applyRerunPolicy(stats *TestStats, policy string) {
+	results, flaky := DeduplicateReruns(stats.Executions, policy)
+	stats.RerunPolicy = policy
+	stats.RerunCount = len(stats.Executions) - len(results)
+	stats.FlakyCount = flaky
+	stats.TestCount, stats.PassCount, stats.FailCount, stats.SkippedCount, stats.ErrorCount = len(results), 0, 0, 0, 0
+	stats.Tests = results
+	stats.Failures = nil
+
+	for _, result := range results {
+		switch result.Status {
+		case "passed":
+			stats.PassCount++
+		case "failed":
+			stats.FailCount++
+		case "skipped":
+			stats.SkippedCount++
+		case "error":
+			stats.ErrorCount++
+		}
+		if result.Failure != nil {
+			stats.Failures = append(stats.Failures, *result.Failure)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const firstRunJunitXml = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.LoginTest" tests="3" failures="2" errors="0" skipped="0">
+  <testcase classname="com.example.LoginTest" name="testLogin"/>
+  <testcase classname="com.example.LoginTest" name="testLogout"><failure message="timeout"/></testcase>
+  <testcase classname="com.example.LoginTest" name="testReset"><failure message="expected 200"/></testcase>
+</testsuite>`
+
+const rerunJunitXml = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.LoginTest" tests="2" failures="1" errors="0" skipped="0">
+  <testcase classname="com.example.LoginTest" name="testLogout"/>
+  <testcase classname="com.example.LoginTest" name="testReset"><failure message="expected 200"/></testcase>
+</testsuite>`
+
+func TestDeduplicateReruns(t *testing.T) {
+	executions := []TestExecution{
+		{ClassName: "A", Name: "flaky", Status: "failed"},
+		{ClassName: "A", Name: "stable", Status: "passed"},
+		{ClassName: "A", Name: "flaky", Status: "passed"},
+		{ClassName: "A", Name: "broken", Status: "passed"},
+		{ClassName: "A", Name: "broken", Status: "error"},
+	}
+	tests := []struct {
+		policy   string
+		statuses []string
+	}{
+		{LastRerunPolicy, []string{"passed", "passed", "error"}},
+		{AnyPassRerunPolicy, []string{"passed", "passed", "passed"}},
+		{AnyFailRerunPolicy, []string{"failed", "passed", "error"}},
+	}
+	for _, tt := range tests {
+		results, flaky := DeduplicateReruns(executions, tt.policy)
+		var statuses []string
+		for _, result := range results {
+			statuses = append(statuses, result.Status)
+		}
+		if !reflect.DeepEqual(statuses, tt.statuses) {
+			t.Errorf("%s: expected %v, got %v", tt.policy, tt.statuses, statuses)
+		}
+		if flaky != 1 || !results[0].Flaky || results[1].Flaky || results[2].Flaky {
+			t.Errorf("%s: expected only the first test to be flaky, got %d flaky in %+v", tt.policy, flaky, results)
+		}
+	}
+}
+
+func TestParseTestsWithRerunPolicy(t *testing.T) {
+	reportsDir := t.TempDir()
+	paths := []string{filepath.Join(reportsDir, "TEST-run1.xml"), filepath.Join(reportsDir, "TEST-run2.xml")}
+	os.WriteFile(paths[0], []byte(firstRunJunitXml), 0644)
+	os.WriteFile(paths[1], []byte(rerunJunitXml), 0644)
+
+	stats, _ := ParseTests(paths, ParseOptions{}, logrus.New())
+	if stats.TestCount != 5 || stats.FailCount != 3 {
+		t.Errorf("Expected every execution counted without a policy, got %d tests and %d failures", stats.TestCount, stats.FailCount)
+	}
+
+	stats, _ = ParseTests(paths, ParseOptions{RerunPolicy: LastRerunPolicy}, logrus.New())
+	if stats.TestCount != 3 || stats.PassCount != 2 || stats.FailCount != 1 || stats.FlakyCount != 1 || stats.RerunCount != 2 {
+		t.Errorf("Unexpected deduplicated stats: %+v", stats)
+	}
+	if len(stats.Failures) != 1 || stats.Failures[0].Name != "testReset" {
+		t.Errorf("Expected only testReset to fail, got %+v", stats.Failures)
+	}
+
+	_, fields := GetJunitDataMaps("", "", stats)
+	if fields[FlakyTestsField] != 1 || fields[RerunTestsField] != 2 {
+		t.Errorf("Expected flaky and rerun fields, got %v", fields)
+	}
+}
+
+func TestGetParseOptionsRerunPolicy(t *testing.T) {
+	options, err := GetParseOptions(Args{RerunPolicy: "Any-Pass"})
+	if err != nil || options.RerunPolicy != AnyPassRerunPolicy {
+		t.Errorf("Expected the any-pass policy, got %+v (%v)", options, err)
+	}
+	if _, err := GetParseOptions(Args{RerunPolicy: "first"}); err == nil {
+		t.Errorf("Expected error for an unsupported rerun policy")
+	}
+}
@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	lp "github.com/influxdata/line-protocol"
+)
+
+const DefaultLineProtocolFile = "influxdb_points.lp"
+
+// LineProtocolFileStore is the InfluxDB dry-run store. Points are appended to
+// a file in InfluxDB line protocol instead of being sent. Reads combine the
+// points in that file with the optional Upstream store, so comparisons still
+// see earlier builds stored in the database.
+type LineProtocolFileStore struct {
+	Path     string
+	Upstream ResultStore
+}
+
+func NewLineProtocolFileStore(path string, upstream ResultStore) *LineProtocolFileStore {
+	if path == "" {
+		path = DefaultLineProtocolFile
+	}
+	return &LineProtocolFileStore{Path: path, Upstream: upstream}
+}
+
+func (l *LineProtocolFileStore) WritePoint(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}) error {
+	return l.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (l *LineProtocolFileStore) WritePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	point := influxdb2.NewPoint(measurement, nonEmptyTags(tags), fields, pointTime)
+	line := write.PointToLineProtocol(point, time.Nanosecond)
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create line protocol directory: %w", err)
+	}
+	file, err := os.OpenFile(l.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open line protocol file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write line protocol file: %w", err)
+	}
+	return nil
+}
+
+func (l *LineProtocolFileStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	records, err := l.ListBuilds(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return fieldsForBuild(records, buildId), nil
+}
+
+func (l *LineProtocolFileStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	var records []BuildRecord
+	if l.Upstream != nil {
+		upstreamRecords, err := l.Upstream.ListBuilds(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, upstreamRecords...)
+	}
+
+	file, err := os.Open(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open line protocol file: %w", err)
+	}
+	defer file.Close()
+
+	parser := lp.NewStreamParser(file)
+	for {
+		metric, err := parser.Next()
+		if err == lp.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid line protocol in %s: %w", l.Path, err)
+		}
+
+		record := BuildRecord{
+			Time:   metric.Time(),
+			Tags:   map[string]string{},
+			Fields: map[string]float64{},
+		}
+		for _, tag := range metric.TagList() {
+			record.Tags[tag.Key] = tag.Value
+		}
+		if !matchesBuildQuery(metric.Name(), record.Tags, query) {
+			continue
+		}
+		for _, field := range metric.FieldList() {
+			if value, ok := toFloat64(field.Value); ok {
+				record.Fields[field.Key] = value
+			}
+		}
+		record.BuildId = record.Tags["buildId"]
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// nonEmptyTags drops tags without a value, which line protocol cannot express.
+func nonEmptyTags(tags map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range tags {
+		if value != "" {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func (l *LineProtocolFileStore) Close() {
+	if l.Upstream != nil {
+		l.Upstream.Close()
+	}
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineProtocolFileStoreDryRun(t *testing.T) {
+	lineProtocolFile := filepath.Join(t.TempDir(), "points.lp")
+	upstream := NewMemoryResultStore()
+	_ = upstream.WritePoint(context.Background(), JacocoTool,
+		map[string]string{"pipelineId": "p1", "buildId": "1", "group": "suite 01"},
+		map[string]interface{}{"line_covered_sum": 100.0})
+
+	store := NewLineProtocolFileStore(lineProtocolFile, upstream)
+	err := PersistResults(context.Background(), store, JacocoTool, "suite 01",
+		map[string]string{"pipelineId": "p1", "buildId": "2"},
+		map[string]interface{}{"line_covered_sum": 110.0, "classes": 4})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	content, err := os.ReadFile(lineProtocolFile)
+	if err != nil {
+		t.Fatalf("Expected line protocol file, got %v", err)
+	}
+	if !strings.HasPrefix(string(content), `jacoco,buildId=2,group=suite\ 01,pipelineId=p1 classes=4i,line_covered_sum=110 `) {
+		t.Errorf("Unexpected line protocol: %q", content)
+	}
+
+	upstreamRecords, _ := upstream.ListBuilds(context.Background(), BuildQuery{Measurement: JacocoTool, PipelineId: "p1", Group: "suite 01"})
+	if len(upstreamRecords) != 1 {
+		t.Errorf("Expected dry run to leave upstream untouched, got %d records", len(upstreamRecords))
+	}
+
+	query := BuildQuery{Measurement: JacocoTool, PipelineId: "p1", Group: "suite 01"}
+	baselines, err := ResolveBaselines(context.Background(), store, query, "2", Args{})
+	if err != nil || baselines[0].BuildId != 1 {
+		t.Errorf("Expected previous build 1 from upstream, got %+v (%v)", baselines, err)
+	}
+	fields, err := store.FetchBuild(context.Background(), query, "2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fields["line_covered_sum"] != 110 || fields["classes"] != 4 {
+		t.Errorf("Unexpected fields read back from line protocol: %v", fields)
+	}
+}
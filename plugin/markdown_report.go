@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultMarkdownReportFile          = "test_results_summary.md"
+	TestResultsMarkdownReportOutputVar = "TEST_RESULTS_MARKDOWN_FILE"
+	MarkdownTopFailures                = 10
+)
+
+// RenderMarkdownReport renders a summary of the build for pull request
+// comments: the totals, the coverage against the first baseline, the module
+// breakdown, the top failures and the quality gate verdict.
+func RenderMarkdownReport(report BuildReport) string {
+	var sb strings.Builder
+
+	var baseline *BaselineComparison
+	if len(report.Comparisons) > 0 {
+		baseline = &report.Comparisons[0]
+	}
+
+	verdict := ""
+	if len(report.Gates) > 0 {
+		verdict = "✅ "
+		if !GatesPassed(report.Gates) {
+			verdict = "❌ "
+		}
+	}
+	fmt.Fprintf(&sb, "## %s%s test results - build %s\n\n", verdict, report.Tool, report.BuildId)
+	if report.Group != "" {
+		fmt.Fprintf(&sb, "Group: `%s`\n\n", report.Group)
+	}
+
+	writeMarkdownTotals(&sb, report, baseline)
+	if report.Tool == JacocoTool {
+		writeMarkdownCoverage(&sb, report, baseline)
+	}
+	writeMarkdownModules(&sb, report.Tool, report.Result.Modules)
+	writeMarkdownFailures(&sb, report.Result.Failures)
+	writeMarkdownGates(&sb, report.Gates)
+
+	return sb.String()
+}
+
+func writeMarkdownTotals(sb *strings.Builder, report BuildReport, baseline *BaselineComparison) {
+	var fieldNames []string
+	for name := range report.Result.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	sb.WriteString("### Totals\n\n")
+	if baseline != nil {
+		fmt.Fprintf(sb, "| Result Type | Value | vs %s |\n|---|---:|---|\n", baseline.Baseline.Label())
+	} else {
+		sb.WriteString("| Result Type | Value |\n|---|---:|\n")
+	}
+
+	for _, name := range fieldNames {
+		value, _ := toFloat64(report.Result.Fields[name])
+		fmt.Fprintf(sb, "| %s | %s |", name, formatMarkdownNumber(value))
+		if baseline != nil {
+			change := "new"
+			for _, diff := range baseline.Diffs {
+				if diff.FieldName == name && diff.IsCompareValid {
+					change = fmt.Sprintf("%s %s (%.2f%%)", markdownArrow(diff.Difference),
+						formatMarkdownDelta(diff.Difference), diff.PercentageDifference)
+				}
+			}
+			fmt.Fprintf(sb, " %s |", change)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
+// writeMarkdownCoverage shows the stored coverage percentages and their
+// change against the baseline. Counter types the baseline does not have are
+// marked as new.
+func writeMarkdownCoverage(sb *strings.Builder, report BuildReport, baseline *BaselineComparison) {
+	current := map[string]float64{}
+	for name, value := range report.Result.Fields {
+		current[name], _ = toFloat64(value)
+	}
+	fillDerivedFields(current)
+
+	sb.WriteString("### Coverage\n\n")
+	if baseline != nil {
+		sb.WriteString("| Coverage Type | Coverage | Baseline | Change |\n|---|---:|---:|---|\n")
+	} else {
+		sb.WriteString("| Coverage Type | Coverage |\n|---|---:|\n")
+	}
+
+	for _, coverageType := range jacocoCoverageTypes {
+		field := coverageType.Field + CoverageFieldSuffix
+		coverage, exists := current[field]
+		if !exists {
+			continue
+		}
+		fmt.Fprintf(sb, "| %s | %.2f%% |", coverageType.Label, coverage)
+		if baseline != nil {
+			baselineCoverage, change := "-", "new"
+			for _, diff := range baseline.Diffs {
+				if diff.FieldName == field && diff.IsCompareValid {
+					baselineCoverage = fmt.Sprintf("%.2f%%", diff.PreviousBuildValue)
+					change = fmt.Sprintf("%s %s", markdownArrow(diff.Difference), formatMarkdownDelta(diff.Difference))
+				}
+			}
+			fmt.Fprintf(sb, " %s | %s |", baselineCoverage, change)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
+func writeMarkdownModules(sb *strings.Builder, tool string, modules []ModuleResult) {
+	if len(modules) == 0 {
+		return
+	}
+	fields := moduleSummaryFields[tool]
+
+	sb.WriteString("### Modules\n\n")
+	sb.WriteString("| Module | " + strings.Join(fields, " | ") + " |\n|---|")
+	sb.WriteString(strings.Repeat("---:|", len(fields)) + "\n")
+	for _, module := range modules {
+		fmt.Fprintf(sb, "| %s |", escapeMarkdownCell(module.Name))
+		for _, field := range fields {
+			value, _ := toFloat64(module.Fields[field])
+			fmt.Fprintf(sb, " %s |", formatMarkdownNumber(value))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
+func writeMarkdownFailures(sb *strings.Builder, failures []TestFailure) {
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "### Top failures (%d)\n\n", len(failures))
+	sb.WriteString("| Test | Message |\n|---|---|\n")
+	for i, failure := range failures {
+		if i == MarkdownTopFailures {
+			fmt.Fprintf(sb, "\n_and %d more_\n", len(failures)-MarkdownTopFailures)
+			break
+		}
+		name := failure.Name
+		if failure.ClassName != "" {
+			name = failure.ClassName + "." + failure.Name
+		}
+		message := firstLine(failure.Message)
+		if message == "" {
+			message = firstLine(failure.StackTrace)
+		} else if failure.Type != "" {
+			message = failure.Type + ": " + message
+		}
+		fmt.Fprintf(sb, "| `%s` | %s |\n", escapeMarkdownCell(name), escapeMarkdownCell(message))
+	}
+	sb.WriteString("\n")
+}
+
+func writeMarkdownGates(sb *strings.Builder, gates []GateResult) {
+	if len(gates) == 0 {
+		return
+	}
+
+	if GatesPassed(gates) {
+		sb.WriteString("### Quality gates: ✅ passed\n\n")
+	} else {
+		sb.WriteString("### Quality gates: ❌ failed\n\n")
+	}
+	sb.WriteString("| Gate | Value | Result |\n|---|---:|---|\n")
+	for _, gate := range gates {
+		value, verdict := "-", "❌"
+		if gate.Found {
+			value = formatMarkdownNumber(gate.Value)
+		}
+		if gate.Passed {
+			verdict = "✅"
+		}
+		fmt.Fprintf(sb, "| `%s` | %s | %s |\n", escapeMarkdownCell(gate.Gate), value, verdict)
+	}
+	sb.WriteString("\n")
+}
+
+func markdownArrow(change float64) string {
+	switch {
+	case change > 0:
+		return "⬆️"
+	case change < 0:
+		return "⬇️"
+	}
+	return "➖"
+}
+
+func formatMarkdownNumber(value float64) string {
+	if value == float64(int64(value)) {
+		return strconv.FormatInt(int64(value), 10)
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func formatMarkdownDelta(value float64) string {
+	if value > 0 {
+		return "+" + formatMarkdownNumber(value)
+	}
+	return formatMarkdownNumber(value)
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if line, _, found := strings.Cut(s, "\n"); found {
+		return strings.TrimSpace(line)
+	}
+	return s
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
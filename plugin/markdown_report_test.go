@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownReport(t *testing.T) {
+	report := BuildReport{
+		Tool:    JunitTool,
+		BuildId: mockBuildNumber,
+		Result: AggregateResult{
+			Fields: map[string]interface{}{"total_tests": 12, "failed_tests": 1},
+			Failures: []TestFailure{
+				{ClassName: "com.example.LoginTest", Name: "testLogin", Status: "failed", Message: "expected a|b\n\tat LoginTest.java:12"},
+			},
+		},
+		Comparisons: []BaselineComparison{{
+			Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 200},
+			Diffs: ComputeResultDiffs(map[string]float64{"total_tests": 12, "failed_tests": 1},
+				map[string]float64{"total_tests": 10, "failed_tests": 1}),
+		}},
+		Gates: []GateResult{{Gate: "failed_tests<=0", Value: 1, Found: true, Passed: false}},
+	}
+
+	markdown := RenderMarkdownReport(report)
+	expectedParts := []string{
+		"## ❌ junit test results - build 201",
+		"| Result Type | Value | vs previous (build 200) |",
+		"| total_tests | 12 | ⬆️ +2 (20.00%) |",
+		"| failed_tests | 1 | ➖ 0 (0.00%) |",
+		"| `com.example.LoginTest.testLogin` | expected a\\|b |",
+		"### Quality gates: ❌ failed",
+		"| `failed_tests<=0` | 1 | ❌ |",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(markdown, part) {
+			t.Errorf("Expected Markdown report to contain %q, got:\n%s", part, markdown)
+		}
+	}
+}
+
+func TestRenderMarkdownReportJacocoCoverage(t *testing.T) {
+	current := map[string]float64{"line_covered_sum": 90, "line_missed_sum": 10, "branch_covered_sum": 3, "branch_missed_sum": 1}
+	previous := map[string]float64{"line_covered_sum": 80, "line_missed_sum": 20}
+	fields := map[string]interface{}{}
+	for key, value := range current {
+		fields[key] = value
+	}
+	AddDerivedFields(fields)
+	fillDerivedFields(current)
+	fillDerivedFields(previous)
+	report := BuildReport{
+		Tool:   JacocoTool,
+		Result: AggregateResult{Fields: fields},
+		Comparisons: []BaselineComparison{{
+			Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 1},
+			Diffs:    ComputeResultDiffs(current, previous),
+		}},
+	}
+
+	markdown := RenderMarkdownReport(report)
+	if !strings.Contains(markdown, "| Line | 90.00% | 80.00% | ⬆️ +10 |") {
+		t.Errorf("Expected line coverage row, got:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "| Branch | 75.00% | - | new |") {
+		t.Errorf("Expected branch coverage without baseline to be new, got:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "| Method |") {
+		t.Errorf("Expected no row for counter types without coverage, got:\n%s", markdown)
+	}
+}
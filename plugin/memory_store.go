@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryResultStore is an in-process ResultStore. Points are lost when the
+// process exits, which makes it suited to tests and dry runs.
+type MemoryResultStore struct {
+	mu     sync.Mutex
+	points []memoryPoint
+}
+
+type memoryPoint struct {
+	measurement string
+	record      BuildRecord
+}
+
+func NewMemoryResultStore() *MemoryResultStore {
+	return &MemoryResultStore{}
+}
+
+func (m *MemoryResultStore) WritePoint(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}) error {
+	return m.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (m *MemoryResultStore) WritePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	record := BuildRecord{
+		BuildId: tags["buildId"],
+		Time:    pointTime,
+		Tags:    map[string]string{},
+		Fields:  map[string]float64{},
+	}
+	for key, value := range tags {
+		record.Tags[key] = value
+	}
+	for key, value := range fields {
+		field, ok := toFloat64(value)
+		if !ok {
+			return fmt.Errorf("unsupported value %v for field %s", value, key)
+		}
+		record.Fields[key] = field
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.points = append(m.points, memoryPoint{measurement: measurement, record: record})
+	return nil
+}
+
+func (m *MemoryResultStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	records, err := m.ListBuilds(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return fieldsForBuild(records, buildId), nil
+}
+
+func (m *MemoryResultStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var records []BuildRecord
+	for _, point := range m.points {
+		if matchesBuildQuery(point.measurement, point.record.Tags, query) {
+			records = append(records, point.record)
+		}
+	}
+	return records, nil
+}
+
+func (m *MemoryResultStore) Close() {}
+
+// fieldsForBuild merges the fields of every record of buildId, later
+// records overriding earlier ones.
+func fieldsForBuild(records []BuildRecord, buildId string) map[string]float64 {
+	fieldValues := map[string]float64{}
+	for _, record := range records {
+		if record.BuildId != buildId {
+			continue
+		}
+		for key, value := range record.Fields {
+			fieldValues[key] = value
+		}
+	}
+	return fieldValues
+}
+
+func matchesBuildQuery(measurement string, tags map[string]string, query BuildQuery) bool {
+	return measurement == query.Measurement &&
+		tags["pipelineId"] == query.PipelineId &&
+		tags["group"] == query.Group
+}
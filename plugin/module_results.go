@@ -0,0 +1,299 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ModuleMeasurementSuffix keeps the per module points apart from the
+	// build totals, so queries on the tool measurement are not affected.
+	ModuleMeasurementSuffix = "_modules"
+	ModuleTag               = "module"
+)
+
+// moduleSummaryFields are the columns of the module breakdown table.
+var moduleSummaryFields = map[string][]string{
+	JunitTool:  {"total_tests", "failed_tests", "errors_count", "skipped_tests", PassRateField},
+	TestNgTool: {"total_cases", "total_failed", "total_skipped", PassRateField},
+	NunitTool:  {"total_cases", "total_failed", "total_skipped", PassRateField},
+	JacocoTool: {"line" + CoverageFieldSuffix, "branch" + CoverageFieldSuffix,
+		"instruction" + CoverageFieldSuffix, "method" + CoverageFieldSuffix},
+}
+
+// ModuleResult holds the fields summed over the report files of one module.
+type ModuleResult struct {
+	Name   string                 `json:"name"`
+	Files  []string               `json:"files"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type ModuleComparison struct {
+	Module string       `json:"module"`
+	Diffs  []ResultDiff `json:"diffs"`
+}
+
+func ModuleBreakdownEnabled(args Args) bool {
+	return args.ModuleBreakdown || args.ModulePattern != ""
+}
+
+// GetModuleName returns the module of a report path: the "module" named
+// group of the pattern, else its first group, else the whole match. Paths the
+// pattern does not match, or any path without a pattern, are their own module.
+func GetModuleName(path string, pattern *regexp.Regexp) string {
+	path = filepath.ToSlash(path)
+	if pattern == nil {
+		return path
+	}
+	match := pattern.FindStringSubmatch(path)
+	if match == nil {
+		return path
+	}
+	if index := pattern.SubexpIndex(ModuleTag); index > 0 && match[index] != "" {
+		return match[index]
+	}
+	if len(match) > 1 && match[1] != "" {
+		return match[1]
+	}
+	return match[0]
+}
+
+// GetModuleResults groups the report files by module and sums their raw
+// counts. The derived percentages are computed again from the sums.
+func GetModuleResults(files []FileResult, modulePattern string) ([]ModuleResult, error) {
+	var pattern *regexp.Regexp
+	if modulePattern != "" {
+		var err error
+		pattern, err = regexp.Compile(modulePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid module pattern %s: %w", modulePattern, err)
+		}
+	}
+
+	moduleIndex := map[string]int{}
+	var modules []ModuleResult
+	for _, file := range files {
+		name := GetModuleName(file.Path, pattern)
+		index, exists := moduleIndex[name]
+		if !exists {
+			index = len(modules)
+			moduleIndex[name] = index
+			modules = append(modules, ModuleResult{Name: name, Fields: map[string]interface{}{}})
+		}
+
+		module := &modules[index]
+		module.Files = append(module.Files, file.Path)
+		for field, value := range file.Fields {
+			if isDerivedField(field) {
+				continue
+			}
+			number, ok := toFloat64(value)
+			if !ok {
+				continue
+			}
+			sum, _ := toFloat64(module.Fields[field])
+			module.Fields[field] = sum + number
+		}
+	}
+
+	for _, module := range modules {
+		AddDerivedFields(module.Fields)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules, nil
+}
+
+func isDerivedField(field string) bool {
+	switch field {
+	case PassRateField, FailureRateField, SkipRateField:
+		return true
+	}
+	return strings.HasSuffix(field, CoverageFieldSuffix)
+}
+
+func ShowModuleResults(tool string, modules []ModuleResult) {
+	if len(modules) == 0 {
+		return
+	}
+	fields := moduleSummaryFields[tool]
+
+	widths := []int{len("Module")}
+	for _, field := range fields {
+		widths = append(widths, len(field))
+	}
+	rows := [][]string{}
+	for _, module := range modules {
+		row := []string{module.Name}
+		for _, field := range fields {
+			value, _ := toFloat64(module.Fields[field])
+			row = append(row, formatMarkdownNumber(value))
+		}
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+		rows = append(rows, row)
+	}
+
+	tableWidth := len(widths)*3 + 1
+	for _, width := range widths {
+		tableWidth += width
+	}
+	printRow := func(row []string) {
+		for i, cell := range row {
+			fmt.Printf("| %-*s ", widths[i], cell)
+		}
+		fmt.Println("|")
+	}
+
+	fmt.Println(strings.Repeat("=", tableWidth))
+	fmt.Println("  Results by module")
+	fmt.Println(strings.Repeat("=", tableWidth))
+	printRow(append([]string{"Module"}, fields...))
+	fmt.Println(strings.Repeat("-", tableWidth))
+	for _, row := range rows {
+		printRow(row)
+	}
+	fmt.Println(strings.Repeat("=", tableWidth))
+}
+
+// PersistModuleResults writes one point per module to the tool's module
+// measurement, tagged with the build tags and the module name.
+func PersistModuleResults(ctx context.Context, store ResultStore, tool string, tags map[string]string, modules []ModuleResult) error {
+	if store == nil {
+		return nil
+	}
+	for _, module := range modules {
+		moduleTags := map[string]string{}
+		for key, value := range tags {
+			moduleTags[key] = value
+		}
+		moduleTags[ModuleTag] = module.Name
+		if err := store.WritePoint(ctx, tool+ModuleMeasurementSuffix, moduleTags, module.Fields); err != nil {
+			return fmt.Errorf("error writing module %s: %w", module.Name, err)
+		}
+	}
+	return nil
+}
+
+// CompareModuleResults compares every module of the current build with the
+// same module in each baseline and adds the differences to the comparisons.
+// Only the modules that changed are printed.
+func CompareModuleResults(ctx context.Context, store ResultStore, query BuildQuery, currentBuildId string, comparisons []BaselineComparison) error {
+	query.Measurement += ModuleMeasurementSuffix
+	records, err := store.ListBuilds(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error fetching module results: %w", err)
+	}
+	currentModules := moduleFieldsForBuild(records, currentBuildId)
+
+	for i := range comparisons {
+		baseline := comparisons[i].Baseline
+		baselineModules := moduleFieldsForBuild(records, strconv.Itoa(baseline.BuildId))
+
+		var names []string
+		for name := range currentModules {
+			names = append(names, name)
+		}
+		for name := range baselineModules {
+			if _, exists := currentModules[name]; !exists {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		comparisons[i].Modules = nil
+		for _, name := range names {
+			currentValues, baselineValues := currentModules[name], baselineModules[name]
+			if currentValues == nil {
+				currentValues = map[string]float64{}
+			}
+			if baselineValues == nil {
+				baselineValues = map[string]float64{}
+			}
+			diffs := ComputeResultDiffs(currentValues, baselineValues)
+			for j := range diffs {
+				diffs[j].Module = name
+				diffs[j].Baseline, diffs[j].BaselineBuildId = baseline.Selector, baseline.BuildId
+			}
+			comparisons[i].Modules = append(comparisons[i].Modules, ModuleComparison{Module: name, Diffs: diffs})
+		}
+
+		fmt.Println("")
+		fmt.Println("Module comparison results with " + baseline.Label() + ":")
+		ShowModuleDiffsAsTable(comparisons[i].Modules)
+	}
+	return nil
+}
+
+func moduleFieldsForBuild(records []BuildRecord, buildId string) map[string]map[string]float64 {
+	modules := map[string]map[string]float64{}
+	for _, record := range records {
+		if record.BuildId != buildId {
+			continue
+		}
+		name := record.Tags[ModuleTag]
+		if modules[name] == nil {
+			modules[name] = map[string]float64{}
+		}
+		for key, value := range record.Fields {
+			modules[name][key] = value
+		}
+	}
+	for _, values := range modules {
+		fillDerivedFields(values)
+	}
+	return modules
+}
+
+// ShowModuleDiffsAsTable prints the fields that changed in each module, and
+// the modules found in only one of the builds.
+func ShowModuleDiffsAsTable(modules []ModuleComparison) {
+	header := []string{"Module", "Result Type", "Current Build", "Previous Build", "Difference"}
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len(title)
+	}
+
+	var rows [][]string
+	for _, module := range modules {
+		for _, diff := range module.Diffs {
+			if diff.Difference == 0 && diff.IsCompareValid {
+				continue
+			}
+			row := []string{module.Module, diff.FieldName, formatMarkdownNumber(diff.CurrentBuildValue),
+				formatMarkdownNumber(diff.PreviousBuildValue), formatMarkdownDelta(diff.Difference)}
+			for i, cell := range row {
+				widths[i] = max(widths[i], len(cell))
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No module changed")
+		return
+	}
+
+	tableWidth := len(widths)*3 + 1
+	for _, width := range widths {
+		tableWidth += width
+	}
+	printRow := func(row []string) {
+		for i, cell := range row {
+			fmt.Printf("| %-*s ", widths[i], cell)
+		}
+		fmt.Println("|")
+	}
+
+	fmt.Println(strings.Repeat("-", tableWidth))
+	printRow(header)
+	fmt.Println(strings.Repeat("-", tableWidth))
+	for _, row := range rows {
+		printRow(row)
+	}
+	fmt.Println(strings.Repeat("-", tableWidth))
+}
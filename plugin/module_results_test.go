@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGetModuleName(t *testing.T) {
+	tests := []struct {
+		path     string
+		pattern  string
+		expected string
+	}{
+		{"services/api/target/surefire-reports/TEST-a.xml", "", "services/api/target/surefire-reports/TEST-a.xml"},
+		{"services/api/target/surefire-reports/TEST-a.xml", `^services/([^/]+)/`, "api"},
+		{"services/api/target/surefire-reports/TEST-a.xml", `(target)|services/(?P<module>[^/]+)`, "api"},
+		{"libs/core/target/TEST-b.xml", `^[^/]+/[^/]+`, "libs/core"},
+		{"TEST-c.xml", `^services/([^/]+)/`, "TEST-c.xml"},
+	}
+	for _, tt := range tests {
+		var pattern *regexp.Regexp
+		if tt.pattern != "" {
+			pattern = regexp.MustCompile(tt.pattern)
+		}
+		if name := GetModuleName(tt.path, pattern); name != tt.expected {
+			t.Errorf("Expected module %s for %s with %q, got %s", tt.expected, tt.path, tt.pattern, name)
+		}
+	}
+}
+
+func TestGetModuleResults(t *testing.T) {
+	_, apiA := GetJunitDataMaps("", "", TestStats{TestCount: 4, PassCount: 3, FailCount: 1})
+	_, apiB := GetJunitDataMaps("", "", TestStats{TestCount: 6, PassCount: 6})
+	_, web := GetJunitDataMaps("", "", TestStats{TestCount: 2, PassCount: 1, SkippedCount: 1})
+	files := []FileResult{
+		{Path: "web/TEST-c.xml", Fields: web},
+		{Path: "api/TEST-a.xml", Fields: apiA},
+		{Path: "api/TEST-b.xml", Fields: apiB},
+	}
+
+	modules, err := GetModuleResults(files, `^([^/]+)/`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(modules) != 2 || modules[0].Name != "api" || modules[1].Name != "web" {
+		t.Fatalf("Unexpected modules: %+v", modules)
+	}
+	if modules[0].Fields["total_tests"] != 10.0 || modules[0].Fields["failed_tests"] != 1.0 || len(modules[0].Files) != 2 {
+		t.Errorf("Expected api counts to be summed, got %+v", modules[0])
+	}
+	if modules[0].Fields[PassRateField] != 90.0 {
+		t.Errorf("Expected api pass_rate to be derived from the sums, got %v", modules[0].Fields[PassRateField])
+	}
+
+	if _, err := GetModuleResults(files, `(`); err == nil {
+		t.Errorf("Expected error for an invalid module pattern")
+	}
+}
+
+func TestCompareModuleResults(t *testing.T) {
+	store := NewMemoryResultStore()
+	query := BuildQuery{Measurement: JunitTool, PipelineId: "pipe_1", Group: "suite_01"}
+	builds := map[string][]ModuleResult{
+		"1": {
+			{Name: "api", Fields: map[string]interface{}{"total_tests": 10, "failed_tests": 0}},
+			{Name: "legacy", Fields: map[string]interface{}{"total_tests": 3, "failed_tests": 0}},
+		},
+		"2": {
+			{Name: "api", Fields: map[string]interface{}{"total_tests": 10, "failed_tests": 2}},
+		},
+	}
+	for buildId, modules := range builds {
+		tags := map[string]string{"pipelineId": "pipe_1", "buildId": buildId, "group": "suite_01"}
+		if err := PersistModuleResults(context.Background(), store, JunitTool, tags, modules); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if records, _ := store.ListBuilds(context.Background(), query); len(records) != 0 {
+		t.Errorf("Expected module points to be kept out of the tool measurement, got %v", records)
+	}
+
+	comparisons := []BaselineComparison{{Baseline: Baseline{Selector: PreviousBuildStrategy, BuildId: 1}}}
+	if err := CompareModuleResults(context.Background(), store, query, "2", comparisons); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	modules := comparisons[0].Modules
+	if len(modules) != 2 || modules[0].Module != "api" || modules[1].Module != "legacy" {
+		t.Fatalf("Unexpected module comparisons: %+v", modules)
+	}
+	for _, diff := range modules[0].Diffs {
+		if diff.FieldName == "failed_tests" && (diff.Difference != 2 || diff.Change != RegressedChange || diff.Module != "api") {
+			t.Errorf("Unexpected failed_tests diff: %+v", diff)
+		}
+	}
+
+	markdown := ComparisonsToMarkdown(comparisons)
+	if !strings.Contains(markdown, "| api | failed_tests | 2 | 0 | ⬆️ +2 | ❌ regressed |") {
+		t.Errorf("Expected the api regression in the Markdown diff, got %s", markdown)
+	}
+}
@@ -7,10 +7,10 @@ import (
 )
 
 type NunitAggregator struct {
-	ReportsDir  string
-	ReportsName string
-	Includes    string
-	DbCredentials
+	ReportsDir   string
+	ReportsName  string
+	Includes     string
+	ParseOptions ParseOptions
 }
 
 type TestRunSummary struct {
@@ -22,37 +22,31 @@ type TestRunSummary struct {
 }
 
 func GetNewNunitAggregator(
-	reportsDir, reportsName, includes, dbUrl, dbToken, dbOrg, dbBucket string) *NunitAggregator {
+	reportsDir, reportsName, includes string, options ParseOptions) *NunitAggregator {
 	return &NunitAggregator{
-		ReportsDir:  reportsDir,
-		ReportsName: reportsName,
-		Includes:    includes,
-		DbCredentials: DbCredentials{
-			InfluxDBURL:   dbUrl,
-			InfluxDBToken: dbToken,
-			Organization:  dbOrg,
-			Bucket:        dbBucket,
-		},
+		ReportsDir:   reportsDir,
+		ReportsName:  reportsName,
+		Includes:     includes,
+		ParseOptions: options,
 	}
 }
 
-func (n *NunitAggregator) Aggregate(groupName string) error {
+func (n *NunitAggregator) Aggregate() (AggregateResult, error) {
 	logrus.Println("NUnit Aggregator Aggregate (Using <test-run> Summary)")
 
-	tagsMap, fieldsMap, err := Aggregate[TestRunSummary](n.ReportsDir, n.Includes,
-		n.DbCredentials.InfluxDBURL, n.DbCredentials.InfluxDBToken,
-		n.DbCredentials.Organization, n.DbCredentials.Bucket, NunitTool, groupName,
+	_, result, err := Aggregate[TestRunSummary](n.ReportsDir, n.Includes, n.ParseOptions,
 		CalculateNunitAggregate, GetNunitDataMaps, ShowNunitStats)
+	result.Tool = NunitTool
 	if err != nil {
-		return fmt.Errorf("failed to aggregate NUnit test results: %w", err)
+		return result, fmt.Errorf("failed to aggregate NUnit test results: %w", err)
 	}
 
-	err = ExportNunitOutputVars(tagsMap, fieldsMap)
+	err = ExportNunitOutputVars(result.Tags, result.Fields)
 	if err != nil {
 		logrus.Println("Error exporting Nunit output variables", err)
-		return err
+		return result, err
 	}
-	return nil
+	return result, nil
 }
 
 func CalculateNunitAggregate(reports []TestRunSummary) TestRunSummary {
@@ -86,6 +80,7 @@ func GetNunitDataMaps(pipelineId, buildNumber string, aggregateData TestRunSumma
 		"total_failed":  aggregateData.TotalFailed,
 		"total_skipped": aggregateData.TotalSkipped,
 	}
+	AddDerivedFields(fields)
 
 	return tags, fields
 }
@@ -124,6 +119,7 @@ func ShowNunitStats(tags map[string]string, fields map[string]interface{}) error
 		fmt.Sprintf("| ✅ Total Passed  | %10.0f |", float64(fields["total_passed"].(int))),
 		fmt.Sprintf("| ❌ Total Failed  | %10.0f |", float64(fields["total_failed"].(int))),
 		fmt.Sprintf("| ⏸️ Total Skipped | %10.0f |", float64(fields["total_skipped"].(int))),
+		fmt.Sprintf("| 📈 Pass Rate     | %9.2f%% |", fields[PassRateField]),
 		border,
 	}
 
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	LenientParseMode = "lenient"
+	StrictParseMode  = "strict"
+)
+
+// ParseOptions controls what happens with report files that cannot be
+// parsed. In lenient mode they are skipped and listed, in strict mode the
+// run fails. Unless AllowEmptyReports is set, a run without any parsed
+// report fails in both modes. Workers is the number of files parsed at the
+// same time. ArchivePattern selects the reports inside matched archives, and
+// the matched files are left out when they match ExcludePattern.
+type ParseOptions struct {
+	Mode              string
+	AllowEmptyReports bool
+	Workers           int
+	ArchivePattern    string
+	ExcludePattern    string
+	// RerunPolicy merges the executions of the same JUnit test when set.
+	RerunPolicy string
+}
+
+// SkippedFile is a matched report file that was not aggregated.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+func GetParseOptions(args Args) (ParseOptions, error) {
+	mode := strings.ToLower(strings.TrimSpace(args.ParseMode))
+	switch mode {
+	case "":
+		mode = LenientParseMode
+	case LenientParseMode, StrictParseMode:
+	default:
+		return ParseOptions{}, fmt.Errorf("parse mode %s not supported, use %s or %s", args.ParseMode, LenientParseMode, StrictParseMode)
+	}
+	if args.ParseWorkers < 0 {
+		return ParseOptions{}, fmt.Errorf("parse workers must not be negative, got %d", args.ParseWorkers)
+	}
+	archivePattern := strings.TrimSpace(args.ArchivePattern)
+	if archivePattern == "" {
+		archivePattern = DefaultArchivePattern
+	}
+	if !doublestar.ValidatePattern(archivePattern) {
+		return ParseOptions{}, fmt.Errorf("invalid archive pattern %s", args.ArchivePattern)
+	}
+	rerunPolicy := strings.ToLower(strings.TrimSpace(args.RerunPolicy))
+	if err := validateRerunPolicy(rerunPolicy); err != nil {
+		return ParseOptions{}, err
+	}
+	return ParseOptions{Mode: mode, AllowEmptyReports: args.AllowEmptyReports, Workers: args.ParseWorkers,
+		ArchivePattern: archivePattern, ExcludePattern: args.ExcludePattern, RerunPolicy: rerunPolicy}, nil
+}
+
+// ParseWorkers returns the number of parse workers, one per CPU when not set.
+func (o ParseOptions) ParseWorkers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// parseFiles parses the files with a pool of workers. The results and errors
+// are at the index of their file, so the merged results do not depend on
+// which worker finished first.
+func parseFiles[S, R any](files []S, workers int, parse func(file S) (R, error)) ([]R, []error) {
+	results := make([]R, len(files))
+	errs := make([]error, len(files))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = parse(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, errs
+}
+
+// CheckParsedReports prints the skipped files and returns an error when the
+// options do not accept the outcome of parsing.
+func CheckParsedReports(options ParseOptions, matched, parsed int, skipped []SkippedFile) error {
+	ShowSkippedFiles(matched, skipped)
+
+	if len(skipped) > 0 && options.Mode == StrictParseMode {
+		return fmt.Errorf("%w: %d of %d report files could not be parsed in %s mode", ErrParse, len(skipped), matched, StrictParseMode)
+	}
+	if parsed == 0 && !options.AllowEmptyReports {
+		if matched == 0 {
+			return fmt.Errorf("%w: no report files matched include_pattern, set allow_empty_reports to accept it", ErrNoReports)
+		}
+		return fmt.Errorf("%w: none of the matched report files could be parsed, set allow_empty_reports to accept it", ErrParse)
+	}
+	return nil
+}
+
+func ShowSkippedFiles(matched int, skipped []SkippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+	maxPathLen := len("Report File")
+	for _, file := range skipped {
+		maxPathLen = max(maxPathLen, len(file.Path))
+	}
+	rowFormat := fmt.Sprintf("| %%-%ds | %%s\n", maxPathLen)
+	separator := strings.Repeat("-", maxPathLen+20)
+
+	fmt.Printf("Skipped %d of %d report files\n", len(skipped), matched)
+	fmt.Println(separator)
+	fmt.Printf(rowFormat, "Report File", "Reason")
+	fmt.Println(separator)
+	for _, file := range skipped {
+		fmt.Printf(rowFormat, file.Path, file.Reason)
+	}
+	fmt.Println(separator)
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestGetParseOptions(t *testing.T) {
+	options, err := GetParseOptions(Args{})
+	if err != nil || options.Mode != LenientParseMode || options.ArchivePattern != DefaultArchivePattern {
+		t.Errorf("Expected lenient mode and the default archive pattern, got %+v (%v)", options, err)
+	}
+	options, err = GetParseOptions(Args{ParseMode: "Strict", AllowEmptyReports: true})
+	if err != nil || options.Mode != StrictParseMode || !options.AllowEmptyReports {
+		t.Errorf("Expected strict mode allowing empty reports, got %+v (%v)", options, err)
+	}
+	if _, err = GetParseOptions(Args{ParseMode: "fast"}); err == nil {
+		t.Errorf("Expected error for an unsupported parse mode")
+	}
+	if _, err = GetParseOptions(Args{ArchivePattern: "reports/[a"}); err == nil {
+		t.Errorf("Expected error for an invalid archive pattern")
+	}
+}
+
+func TestAggregateParseModes(t *testing.T) {
+	reportsDir := t.TempDir()
+	files := map[string]string{
+		"a/TestResult.xml": NunitTestXml,
+		"b/TestResult.xml": "<test-run total=\"3\"",
+	}
+	for name, content := range files {
+		path := filepath.Join(reportsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error creating report dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing report: %v", err)
+		}
+	}
+	t.Setenv(PipeLineIdEnvVar, mockPipelineId)
+	t.Setenv(BuildNumberEnvVar, mockBuildNumber)
+	showStats := func(map[string]string, map[string]interface{}) error { return nil }
+
+	_, result, err := Aggregate[TestRunSummary](reportsDir, "**/TestResult.xml", ParseOptions{Mode: LenientParseMode},
+		CalculateNunitAggregate, GetNunitDataMaps, showStats)
+	if err != nil {
+		t.Fatalf("Expected no error in lenient mode, got %v", err)
+	}
+	if len(result.Files) != 1 || len(result.SkippedFiles) != 1 || result.SkippedFiles[0].Path != "b/TestResult.xml" ||
+		!strings.Contains(result.SkippedFiles[0].Reason, "invalid XML") {
+		t.Errorf("Expected b/TestResult.xml to be skipped as invalid XML, got %+v", result.SkippedFiles)
+	}
+
+	_, result, err = Aggregate[TestRunSummary](reportsDir, "**/TestResult.xml", ParseOptions{Mode: StrictParseMode},
+		CalculateNunitAggregate, GetNunitDataMaps, showStats)
+	if err == nil || len(result.SkippedFiles) != 1 {
+		t.Errorf("Expected strict mode to fail and list the skipped file, got %v (%+v)", err, result.SkippedFiles)
+	}
+
+	_, _, err = Aggregate[TestRunSummary](reportsDir, "**/missing.xml", ParseOptions{Mode: LenientParseMode},
+		CalculateNunitAggregate, GetNunitDataMaps, showStats)
+	if err == nil || !strings.Contains(err.Error(), "no report files matched") {
+		t.Errorf("Expected error when no report matches, got %v", err)
+	}
+
+	_, _, err = Aggregate[TestRunSummary](reportsDir, "**/missing.xml", ParseOptions{Mode: LenientParseMode, AllowEmptyReports: true},
+		CalculateNunitAggregate, GetNunitDataMaps, showStats)
+	if err != nil {
+		t.Errorf("Expected no error when empty reports are allowed, got %v", err)
+	}
+}
+
+func TestParseFilesKeepsFileOrder(t *testing.T) {
+	paths := []string{"c", "a", "d", "b", "e"}
+	delays := map[string]int{"c": 5, "a": 4, "d": 3, "b": 2, "e": 1}
+	results, errs := parseFiles(paths, 3, func(path string) (string, error) {
+		// the first files finish last
+		time.Sleep(time.Duration(delays[path]) * time.Millisecond)
+		if path == "d" {
+			return "", errors.New("invalid")
+		}
+		return strings.ToUpper(path), nil
+	})
+	if strings.Join(results, ",") != "C,A,,B,E" {
+		t.Errorf("Expected results in file order, got %v", results)
+	}
+	if errs[2] == nil || errs[0] != nil || errs[4] != nil {
+		t.Errorf("Expected only the error of d, got %v", errs)
+	}
+}
+
+func TestParseTestsIsDeterministic(t *testing.T) {
+	reportsDir := t.TempDir()
+	paths := writeJunitReports(t, reportsDir, 40)
+
+	sequential, _ := ParseTests(paths, ParseOptions{Workers: 1}, logrus.New())
+	concurrent, _ := ParseTests(paths, ParseOptions{Workers: 8}, logrus.New())
+	if !reflect.DeepEqual(sequential, concurrent) {
+		t.Errorf("Expected the same stats with 1 and 8 workers")
+	}
+	if len(concurrent.Files) != 40 || concurrent.TestCount != 40*5 {
+		t.Errorf("Unexpected stats: %d files, %d tests", len(concurrent.Files), concurrent.TestCount)
+	}
+}
+
+func writeJunitReports(t testing.TB, dir string, count int) []string {
+	var paths []string
+	for i := 0; i < count; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("TEST-%04d.xml", i))
+		if err := os.WriteFile(path, []byte(JunitReportXml), 0644); err != nil {
+			t.Fatalf("Error writing report: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+var benchmarkWorkers = []int{1, 2, 4, 8}
+
+func BenchmarkParseTests(b *testing.B) {
+	paths := writeJunitReports(b, b.TempDir(), 500)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	for _, workers := range benchmarkWorkers {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseTests(paths, ParseOptions{Workers: workers}, log); err != nil && !strings.Contains(err.Error(), "failed tests") {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetXmlReportData(b *testing.B) {
+	reportsDir := b.TempDir()
+	for i := 0; i < 500; i++ {
+		path := filepath.Join(reportsDir, fmt.Sprintf("TestResult-%04d.xml", i))
+		if err := os.WriteFile(path, []byte(NunitTestXml), 0644); err != nil {
+			b.Fatalf("Error writing report: %v", err)
+		}
+	}
+	logrus.SetOutput(io.Discard)
+	defer logrus.SetOutput(os.Stderr)
+
+	for _, workers := range benchmarkWorkers {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := GetXmlReportData[TestRunSummary](reportsDir, []string{"*.xml"}, ParseOptions{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultPipelineTags are added to every stored point unless PLUGIN_PIPELINE_TAGS
+// overrides them. They have few distinct values per repo, so they are safe as
+// InfluxDB tags; commit, author and pullRequest grow with every build and
+// have to be enabled explicitly.
+const DefaultPipelineTags = "repo,branch,targetBranch,event,buildStatus,stage,step,tag,semver"
+
+// pipelineTagValues maps the supported tag names to their source in the
+// Drone pipeline metadata.
+var pipelineTagValues = map[string]func(p Pipeline) string{
+	"repo": func(p Pipeline) string { return p.Repo.Slug },
+	"branch": func(p Pipeline) string {
+		if p.Commit.Branch != "" {
+			return p.Commit.Branch
+		}
+		return p.Build.Branch
+	},
+	"targetBranch": func(p Pipeline) string { return p.Commit.Target },
+	"event":        func(p Pipeline) string { return p.Build.Event },
+	"buildStatus":  func(p Pipeline) string { return p.Build.Status },
+	"stage":        func(p Pipeline) string { return p.Stage.Name },
+	"step":         func(p Pipeline) string { return p.Step.Name },
+	"tag":          func(p Pipeline) string { return p.Tag.Name },
+	"semver":       func(p Pipeline) string { return p.Semver.Version },
+	"commit":       func(p Pipeline) string { return p.Commit.Rev },
+	"author":       func(p Pipeline) string { return p.Commit.Author.Username },
+	"pullRequest": func(p Pipeline) string {
+		if p.PullRequest.Number == 0 {
+			return ""
+		}
+		return strconv.Itoa(p.PullRequest.Number)
+	},
+}
+
+// GetPipelineTags returns the requested pipeline metadata tags that have a
+// value. tagNames is a comma separated list of tag names, "none" disables the
+// tags and an empty list selects DefaultPipelineTags.
+func GetPipelineTags(pipeline Pipeline, tagNames string) (map[string]string, error) {
+	tags := map[string]string{}
+
+	tagNames = strings.TrimSpace(tagNames)
+	if tagNames == "none" {
+		return tags, nil
+	}
+	if tagNames == "" {
+		tagNames = DefaultPipelineTags
+	}
+
+	for _, name := range strings.Split(tagNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		getValue, ok := pipelineTagValues[name]
+		if !ok {
+			return nil, fmt.Errorf("pipeline tag %s not supported, use one of %s", name, strings.Join(SupportedPipelineTags(), ", "))
+		}
+		if value := getValue(pipeline); value != "" {
+			tags[name] = value
+		}
+	}
+	return tags, nil
+}
+
+func SupportedPipelineTags() []string {
+	var names []string
+	for name := range pipelineTagValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
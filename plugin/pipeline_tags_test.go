@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func mockPipeline() Pipeline {
+	var pipeline Pipeline
+	pipeline.Repo.Slug = "octocat/hello-world"
+	pipeline.Commit.Branch = "feature/login"
+	pipeline.Commit.Target = "main"
+	pipeline.Commit.Rev = "8f51ad7884c5eb69c11d260a31da7a745e6b78e2"
+	pipeline.Commit.Author.Username = "octocat"
+	pipeline.Build.Event = "pull_request"
+	pipeline.PullRequest.Number = 42
+	pipeline.Stage.Name = "build"
+	pipeline.Step.Name = "aggregate"
+	return pipeline
+}
+
+func TestGetPipelineTagsDefaults(t *testing.T) {
+	tags, err := GetPipelineTags(mockPipeline(), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedTags := map[string]string{
+		"repo":         "octocat/hello-world",
+		"branch":       "feature/login",
+		"targetBranch": "main",
+		"event":        "pull_request",
+		"stage":        "build",
+		"step":         "aggregate",
+	}
+	if len(tags) != len(expectedTags) {
+		t.Errorf("Expected %d default tags, got %v", len(expectedTags), tags)
+	}
+	for key, expectedValue := range expectedTags {
+		if tags[key] != expectedValue {
+			t.Errorf("Mismatch in tags: got %s = %v, expected %v", key, tags[key], expectedValue)
+		}
+	}
+}
+
+func TestGetPipelineTagsConfigured(t *testing.T) {
+	tags, err := GetPipelineTags(mockPipeline(), "commit, author,pullRequest")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tags["commit"] != "8f51ad7884c5eb69c11d260a31da7a745e6b78e2" || tags["author"] != "octocat" || tags["pullRequest"] != "42" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if _, ok := tags["branch"]; ok {
+		t.Errorf("Expected only the configured tags, got %v", tags)
+	}
+
+	tags, _ = GetPipelineTags(mockPipeline(), "none")
+	if len(tags) != 0 {
+		t.Errorf("Expected no tags for none, got %v", tags)
+	}
+
+	if _, err := GetPipelineTags(mockPipeline(), "branch,unknown"); err == nil {
+		t.Errorf("Expected error for unsupported tag")
+	}
+}
+
+func TestGetPipelineTagsSkipsEmptyValues(t *testing.T) {
+	tags, err := GetPipelineTags(Pipeline{}, "branch,pullRequest")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Expected no tags for empty metadata, got %v", tags)
+	}
+}
@@ -22,13 +22,55 @@ type Args struct {
 	ReportsDir          string `envconfig:"PLUGIN_REPORTS_DIR"`
 	ReportsName         string `envconfig:"PLUGIN_REPORTS_NAME"`
 	IncludePattern      string `envconfig:"PLUGIN_INCLUDE_PATTERN"`
+	ExcludePattern      string `envconfig:"PLUGIN_EXCLUDE_PATTERN"`
 	DbUrl               string `envconfig:"PLUGIN_INFLUXDB_URL"`
 	DbToken             string `envconfig:"PLUGIN_INFLUXDB_TOKEN"`
 	DbOrg               string `envconfig:"PLUGIN_INFLUXDB_ORG"`
 	DbBucket            string `envconfig:"PLUGIN_INFLUXDB_BUCKET"`
+	DbDatabase          string `envconfig:"PLUGIN_INFLUXDB_DATABASE"`
+	DbRetentionPolicy   string `envconfig:"PLUGIN_INFLUXDB_RETENTION_POLICY"`
+	DbUsername          string `envconfig:"PLUGIN_INFLUXDB_USERNAME"`
+	DbPassword          string `envconfig:"PLUGIN_INFLUXDB_PASSWORD"`
+	DbDryRun            bool   `envconfig:"PLUGIN_INFLUXDB_DRY_RUN"`
+	LineProtocolFile    string `envconfig:"PLUGIN_LINE_PROTOCOL_FILE"`
+	StoreType           string `envconfig:"PLUGIN_STORE_TYPE"`
+	StoreFile           string `envconfig:"PLUGIN_STORE_FILE"`
+	PushgatewayUrl      string `envconfig:"PLUGIN_PUSHGATEWAY_URL"`
+	OpenMetricsFile     string `envconfig:"PLUGIN_OPENMETRICS_FILE"`
+	PipelineTags        string `envconfig:"PLUGIN_PIPELINE_TAGS"`
 	GroupName           string `envconfig:"PLUGIN_GROUP"`
 	CompareBuildResults bool   `envconfig:"PLUGIN_COMPARE_BUILD_RESULTS"`
 	CompareBuildId      string `envconfig:"PLUGIN_COMPARE_BUILD_ID"`
+	CompareStrategy     string `envconfig:"PLUGIN_COMPARE_STRATEGY"`
+	BaselineBranch      string `envconfig:"PLUGIN_BASELINE_BRANCH"`
+	CompareBaselines    string `envconfig:"PLUGIN_COMPARE_BASELINES"`
+	PinBaseline         string `envconfig:"PLUGIN_PIN_BASELINE"`
+	TrendBuilds         int    `envconfig:"PLUGIN_TREND_BUILDS"`
+	TrendWindow         string `envconfig:"PLUGIN_TREND_WINDOW"`
+	HtmlReport          bool   `envconfig:"PLUGIN_HTML_REPORT"`
+	HtmlReportFile      string `envconfig:"PLUGIN_HTML_REPORT_FILE"`
+	MarkdownReport      bool   `envconfig:"PLUGIN_MARKDOWN_REPORT"`
+	MarkdownReportFile  string `envconfig:"PLUGIN_MARKDOWN_REPORT_FILE"`
+	QualityGates        string `envconfig:"PLUGIN_QUALITY_GATES"`
+	CardSchema          string `envconfig:"PLUGIN_CARD_SCHEMA"`
+	ResultsDataFile     string `envconfig:"PLUGIN_RESULTS_DATA_FILE"`
+	ResultsDataAllTests bool   `envconfig:"PLUGIN_RESULTS_DATA_ALL_TESTS"`
+	DiffFormat          string `envconfig:"PLUGIN_DIFF_FORMAT"`
+	DiffOutputDir       string `envconfig:"PLUGIN_DIFF_OUTPUT_DIR"`
+	DiffFileName        string `envconfig:"PLUGIN_DIFF_FILE_NAME"`
+	ModuleBreakdown     bool   `envconfig:"PLUGIN_MODULE_BREAKDOWN"`
+	ModulePattern       string `envconfig:"PLUGIN_MODULE_PATTERN"`
+	ParseMode           string `envconfig:"PLUGIN_PARSE_MODE"`
+	AllowEmptyReports   bool   `envconfig:"PLUGIN_ALLOW_EMPTY_REPORTS"`
+	ParseWorkers        int    `envconfig:"PLUGIN_PARSE_WORKERS"`
+	ArchivePattern      string `envconfig:"PLUGIN_ARCHIVE_PATTERN"`
+	RerunPolicy         string `envconfig:"PLUGIN_RERUN_POLICY"`
+	FailOnTestFailure   bool   `envconfig:"PLUGIN_FAIL_ON_TEST_FAILURE"`
+	WarnOnStoreError    bool   `envconfig:"PLUGIN_WARN_ON_STORE_ERROR"`
+	StoreTimeout        string `envconfig:"PLUGIN_STORE_TIMEOUT"`
+	StoreAttempts       int    `envconfig:"PLUGIN_STORE_ATTEMPTS"`
+	StoreRetryBackoff   string `envconfig:"PLUGIN_STORE_RETRY_BACKOFF"`
+	StoreSpoolFile      string `envconfig:"PLUGIN_STORE_SPOOL_FILE"`
 }
 
 // Exec executes the plugin.
@@ -36,79 +78,202 @@ func Exec(ctx context.Context, args Args) error {
 
 	logrus.Println("tool args.tool ", args.Tool)
 
-	err := StoreResultsToInfluxDb(args)
+	gates, err := ParseQualityGates(args.QualityGates)
 	if err != nil {
 		logrus.Println("error: ", err)
 		return err
 	}
+	if _, err = GetDiffFormats(args.DiffFormat); err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+
+	storeOptions, err := GetStoreOptions(args)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	store, err := NewResultStore(args)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	if store != nil {
+		resilientStore := NewResilientStore(store, storeOptions)
+		store = categorizedStore{resilientStore}
+		defer store.Close()
+
+		// points that are not replayed stay spooled for the next run
+		if err = resilientStore.ReplaySpool(ctx); err != nil {
+			logrus.Warnln("Spooled points not replayed: ", err)
+		}
+	}
+
+	result, err := StoreResults(ctx, args, store)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	err = ExportMetrics(ctx, args, result)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	report := NewBuildReport(args, result)
 	if args.CompareBuildResults || args.CompareBuildId != "" {
-		err = CompareBuildResults(args)
-		if err != nil {
+		report.Comparisons, err = CompareBuildResults(ctx, args, store)
+		if err = StoreErrorAsWarning(args, err); err != nil {
+			logrus.Println("error: ", err)
+			return err
+		}
+	}
+	if args.TrendBuilds > 0 || args.TrendWindow != "" {
+		report.Trend, err = ReportBuildTrend(ctx, args, store)
+		if err = StoreErrorAsWarning(args, err); err != nil {
 			logrus.Println("error: ", err)
 			return err
 		}
 	}
+	if len(gates) > 0 {
+		report.Gates = EvaluateQualityGates(gates, result.Fields, report.Comparisons)
+		fmt.Println("")
+		ShowGateResults(report.Gates)
+		fmt.Println("")
+	}
+
+	err = ExportReports(args, report)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	err = CheckTestFailures(args, result)
+	if err != nil {
+		logrus.Println("error: ", err)
+		return err
+	}
+	if !GatesPassed(report.Gates) {
+		return ErrGateFailed
+	}
 	return nil
 }
 
-func StoreResultsToInfluxDb(args Args) error {
+func StoreResults(ctx context.Context, args Args, store ResultStore) (AggregateResult, error) {
+	result, err := AggregateResults(args)
+	if err != nil {
+		return result, err
+	}
+
+	pipelineTags, err := GetPipelineTags(args.Pipeline, args.PipelineTags)
+	if err != nil {
+		return result, err
+	}
+	for key, value := range pipelineTags {
+		if _, exists := result.Tags[key]; !exists {
+			result.Tags[key] = value
+		}
+	}
+	if args.PinBaseline != "" {
+		result.Tags["baseline"] = args.PinBaseline
+	}
+
+	err = PersistResults(ctx, store, result.Tool, args.GroupName, result.Tags, result.Fields)
+	if err = StoreErrorAsWarning(args, err); err != nil {
+		logrus.Println("Error persisting results: ", err.Error())
+		return result, err
+	}
+
+	if ModuleBreakdownEnabled(args) {
+		result.Modules, err = GetModuleResults(result.Files, args.ModulePattern)
+		if err != nil {
+			return result, err
+		}
+		ShowModuleResults(result.Tool, result.Modules)
+		err = PersistModuleResults(ctx, store, result.Tool, result.Tags, result.Modules)
+		if err = StoreErrorAsWarning(args, err); err != nil {
+			logrus.Println("Error persisting module results: ", err.Error())
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func AggregateResults(args Args) (AggregateResult, error) {
+	options, err := GetParseOptions(args)
+	if err != nil {
+		return AggregateResult{}, err
+	}
+
 	switch args.Tool {
 	case JacocoTool:
-		aggregator := GetNewJacocoAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern,
-			args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket)
-		return aggregator.Aggregate(args.GroupName)
+		aggregator := GetNewJacocoAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern, options)
+		return aggregator.Aggregate()
 	case JunitTool:
-		aggregator := GetNewJunitAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern,
-			args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket)
-		return aggregator.Aggregate(args.GroupName)
+		aggregator := GetNewJunitAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern, options)
+		return aggregator.Aggregate()
 	case NunitTool:
-		aggregator := GetNewNunitAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern,
-			args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket)
-		return aggregator.Aggregate(args.GroupName)
+		aggregator := GetNewNunitAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern, options)
+		return aggregator.Aggregate()
 	case TestNgTool:
-		aggregator := GetNewTestNgAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern,
-			args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket)
-		return aggregator.Aggregate(args.GroupName)
+		aggregator := GetNewTestNgAggregator(args.ReportsDir, args.ReportsName, args.IncludePattern, options)
+		return aggregator.Aggregate()
 	}
 	errStr := fmt.Sprintf("Tool type %s not supported to aggregate", args.Tool)
-	return errors.New(errStr)
+	return AggregateResult{}, errors.New(errStr)
 }
 
-func CompareBuildResults(args Args) error {
-	var resultStr string
+func CompareBuildResults(ctx context.Context, args Args, store ResultStore) ([]BaselineComparison, error) {
+	var comparisons []BaselineComparison
 	var err error
-	diffFileName := BuildResultsDiffCsv
+
+	if store == nil {
+		return nil, errors.New("comparing build results requires a result store, configure InfluxDB or set store_type to file")
+	}
 
 	switch args.Tool {
 	case JacocoTool:
-		resultStr, err = CompareResults(JacocoTool, args)
+		comparisons, err = CompareResults(ctx, JacocoTool, store, args)
 	case JunitTool:
-		resultStr, err = CompareJunitResults(JunitTool, args)
+		comparisons, err = CompareResults(ctx, JunitTool, store, args)
 	case NunitTool:
-		resultStr, err = CompareResults(NunitTool, args)
+		comparisons, err = CompareResults(ctx, NunitTool, store, args)
 	case TestNgTool:
-		resultStr, err = CompareResults(TestNgTool, args)
+		comparisons, err = CompareResults(ctx, TestNgTool, store, args)
 	default:
 		errStr := fmt.Sprintf("Tool type %s not supported to compare builds", args.Tool)
-		return errors.New(errStr)
+		return nil, errors.New(errStr)
 	}
 
 	if err != nil {
 		logrus.Println("Unable to compare results ", err)
-		return err
+		return nil, err
+	}
+
+	if ModuleBreakdownEnabled(args) {
+		pipelineId, buildNumber, err := GetPipelineInfo()
+		if err != nil {
+			return comparisons, err
+		}
+		query := BuildQuery{Measurement: args.Tool, PipelineId: pipelineId, Group: args.GroupName}
+		err = CompareModuleResults(ctx, store, query, buildNumber, comparisons)
+		if err != nil {
+			logrus.Println("Unable to compare module results ", err)
+			return comparisons, err
+		}
 	}
-	err = ExportComparisonResults(diffFileName, resultStr, TestResultsDiffFileOutputVar)
+
+	err = ExportComparisons(args, comparisons)
 	if err != nil {
 		logrus.Println("Unable to export comparison results ", err)
-		return err
+		return comparisons, err
 	}
-	return nil
+	return comparisons, nil
 }
 
 func ExportComparisonResults(resultFileName, resultStr, outputVarName string) error {
 	err := WriteStrToFile(resultFileName, resultStr)
 	if err != nil {
 		logrus.Println("Unable to write comparison results to file ", err)
+		return err
 	}
 	err = WriteToEnvVariable(outputVarName, resultFileName)
 	if err != nil {
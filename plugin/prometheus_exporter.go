@@ -0,0 +1,182 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MetricsNamePrefix      = "test_results"
+	DefaultPushgatewayJob  = "drone-test-result-aggregator"
+	pushgatewayContentType = "text/plain; version=0.0.4; charset=utf-8"
+	pushgatewayTimeout     = 30 * time.Second
+)
+
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// MetricsLabels identify the build a set of exported metrics belongs to.
+// Job, Instance and Group are also used as the Pushgateway grouping key.
+type MetricsLabels struct {
+	Job      string
+	Instance string
+	Group    string
+	Tool     string
+}
+
+func GetMetricsLabels(args Args, tool string) (MetricsLabels, error) {
+	_, buildId, err := GetPipelineInfo()
+	if err != nil {
+		return MetricsLabels{}, err
+	}
+
+	job := args.Repo.Slug
+	if job == "" {
+		job = DefaultPushgatewayJob
+	}
+	return MetricsLabels{
+		Job:      job,
+		Instance: buildId,
+		Group:    args.GroupName,
+		Tool:     tool,
+	}, nil
+}
+
+// ExportMetrics pushes the aggregated fields to a Pushgateway and/or writes
+// them to an OpenMetrics text file, depending on which settings are present.
+func ExportMetrics(ctx context.Context, args Args, result AggregateResult) error {
+	if args.PushgatewayUrl == "" && args.OpenMetricsFile == "" {
+		return nil
+	}
+
+	labels, err := GetMetricsLabels(args, result.Tool)
+	if err != nil {
+		logrus.Println("Error getting the metrics labels: ", err)
+		return err
+	}
+
+	if args.OpenMetricsFile != "" {
+		content := RenderMetrics(result.Fields, labels, true)
+		err := WriteStrToFile(args.OpenMetricsFile, content)
+		if err != nil {
+			logrus.Println("Error writing OpenMetrics file: ", err)
+			return err
+		}
+		logrus.Println("Metrics written to ", args.OpenMetricsFile)
+	}
+
+	if args.PushgatewayUrl != "" {
+		err := PushToGateway(ctx, args.PushgatewayUrl, labels, result.Fields)
+		if err != nil {
+			logrus.Println("Error pushing metrics to Pushgateway: ", err)
+			return err
+		}
+		logrus.Println("Metrics pushed to Pushgateway ", args.PushgatewayUrl)
+	}
+	return nil
+}
+
+// RenderMetrics renders fields as gauges in the Prometheus text format. With
+// openMetrics set, the grouping labels are added to every sample and the
+// output is terminated with "# EOF" as required by OpenMetrics; otherwise the
+// labels are expected to be carried by the Pushgateway URL.
+func RenderMetrics(fields map[string]interface{}, labels MetricsLabels, openMetrics bool) string {
+	var fieldNames []string
+	for field := range fields {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+
+	sampleLabels := [][2]string{{"tool", labels.Tool}}
+	if openMetrics {
+		sampleLabels = append([][2]string{
+			{"job", labels.Job},
+			{"instance", labels.Instance},
+			{"group", labels.Group},
+		}, sampleLabels...)
+	}
+	var labelPairs []string
+	for _, label := range sampleLabels {
+		labelPairs = append(labelPairs, fmt.Sprintf(`%s="%s"`, label[0], escapeLabelValue(label[1])))
+	}
+	labelStr := "{" + strings.Join(labelPairs, ",") + "}"
+
+	var sb strings.Builder
+	for _, field := range fieldNames {
+		value, ok := toFloat64(fields[field])
+		if !ok {
+			continue
+		}
+		name := MetricName(field)
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&sb, "%s%s %s\n", name, labelStr, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	if openMetrics {
+		sb.WriteString("# EOF\n")
+	}
+	return sb.String()
+}
+
+func MetricName(field string) string {
+	return MetricsNamePrefix + "_" + invalidMetricNameChars.ReplaceAllString(field, "_")
+}
+
+func PushToGateway(ctx context.Context, gatewayUrl string, labels MetricsLabels, fields map[string]interface{}) error {
+	pushUrl := strings.TrimSuffix(gatewayUrl, "/") + "/metrics" +
+		pushgatewayPathSegment("job", labels.Job) +
+		pushgatewayPathSegment("instance", labels.Instance)
+	if labels.Group != "" {
+		pushUrl += pushgatewayPathSegment("group", labels.Group)
+	}
+
+	body := RenderMetrics(fields, labels, false)
+	ctx, cancel := context.WithTimeout(ctx, pushgatewayTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, pushUrl, bytes.NewBufferString(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", pushgatewayContentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// pushgatewayPathSegment encodes a grouping key label. Values that are empty
+// or contain a slash (such as a repo slug) use the base64 form the
+// Pushgateway accepts for them, with "=" standing for the empty value.
+func pushgatewayPathSegment(name, value string) string {
+	if value == "" {
+		return "/" + name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.URLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
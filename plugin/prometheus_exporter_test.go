@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMetrics(t *testing.T) {
+	fields := map[string]interface{}{
+		"total_tests":  10,
+		"failed_tests": 2,
+		"duration_ms":  150.5,
+	}
+	labels := MetricsLabels{Job: "octocat/hello-world", Instance: "42", Group: "suite_01", Tool: JunitTool}
+
+	openMetrics := RenderMetrics(fields, labels, true)
+	expectedLines := []string{
+		"# TYPE test_results_duration_ms gauge",
+		`test_results_duration_ms{job="octocat/hello-world",instance="42",group="suite_01",tool="junit"} 150.5`,
+		`test_results_total_tests{job="octocat/hello-world",instance="42",group="suite_01",tool="junit"} 10`,
+		"# EOF",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(openMetrics, expectedLine+"\n") {
+			t.Errorf("Expected line %q in:\n%s", expectedLine, openMetrics)
+		}
+	}
+
+	pushBody := RenderMetrics(fields, labels, false)
+	if strings.Contains(pushBody, "# EOF") || strings.Contains(pushBody, "job=") {
+		t.Errorf("Expected push body without EOF marker and grouping labels, got:\n%s", pushBody)
+	}
+}
+
+func TestPushToGateway(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	labels := MetricsLabels{Job: "octocat/hello-world", Instance: "42", Group: "suite_01", Tool: JacocoTool}
+	err := PushToGateway(context.Background(), server.URL, labels, map[string]interface{}{"line_covered_sum": 122.0})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", gotMethod)
+	}
+	expectedPath := "/metrics/job@base64/b2N0b2NhdC9oZWxsby13b3JsZA==/instance/42/group/suite_01"
+	if gotPath != expectedPath {
+		t.Errorf("Expected path %s, got %s", expectedPath, gotPath)
+	}
+	if !strings.Contains(gotBody, `test_results_line_covered_sum{tool="jacoco"} 122`) {
+		t.Errorf("Unexpected push body:\n%s", gotBody)
+	}
+}
+
+func TestGetMetricsLabelsUsesHarnessBuildId(t *testing.T) {
+	t.Setenv(PipeLineIdEnvVar, mockPipelineId)
+	t.Setenv(BuildNumberEnvVar, "1234")
+
+	args := Args{GroupName: "suite_01"}
+	args.Repo.Slug = "octocat/hello-world"
+	args.Build.Number = 7
+	labels, err := GetMetricsLabels(args, JunitTool)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if labels.Instance != "1234" || labels.Job != "octocat/hello-world" {
+		t.Errorf("Expected the instance from HARNESS_BUILD_ID, got %+v", labels)
+	}
+
+	t.Setenv(BuildNumberEnvVar, "")
+	if _, err := GetMetricsLabels(args, JunitTool); err == nil {
+		t.Errorf("Expected error without HARNESS_BUILD_ID")
+	}
+}
+
+func TestPushToGatewayStopsWhenCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := PushToGateway(ctx, server.URL, MetricsLabels{Job: "job", Instance: "42"}, map[string]interface{}{"total_tests": 1})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the push to stop with the context, got %v after %s", err, time.Since(start))
+	}
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeltaGatePrefix makes a quality gate check the difference of a field with
+// the first baseline instead of its current value, for example
+// "delta.failed_tests<=0".
+const DeltaGatePrefix = "delta."
+
+var gateOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// QualityGate is a threshold on one field, for example "failed_tests<=0".
+type QualityGate struct {
+	Expression string
+	Field      string
+	Operator   string
+	Threshold  float64
+}
+
+type GateResult struct {
+	Gate    string  `json:"gate"`
+	Value   float64 `json:"value"`
+	Found   bool    `json:"found"`
+	Passed  bool    `json:"passed"`
+	Message string  `json:"message,omitempty"`
+}
+
+// ParseQualityGates parses a comma separated list of gates of the form
+// <field><operator><number>.
+func ParseQualityGates(spec string) ([]QualityGate, error) {
+	var gates []QualityGate
+	for _, expression := range strings.Split(spec, ",") {
+		expression = strings.TrimSpace(expression)
+		if expression == "" {
+			continue
+		}
+		gate, err := parseQualityGate(expression)
+		if err != nil {
+			return nil, err
+		}
+		gates = append(gates, gate)
+	}
+	return gates, nil
+}
+
+func parseQualityGate(expression string) (QualityGate, error) {
+	for _, operator := range gateOperators {
+		field, thresholdStr, found := strings.Cut(expression, operator)
+		if !found {
+			continue
+		}
+		field = strings.TrimSpace(field)
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(thresholdStr), 64)
+		if field == "" || err != nil {
+			return QualityGate{}, fmt.Errorf("invalid quality gate %s", expression)
+		}
+		return QualityGate{Expression: expression, Field: field, Operator: operator, Threshold: threshold}, nil
+	}
+	return QualityGate{}, fmt.Errorf("invalid quality gate %s, use one of the operators %s",
+		expression, strings.Join(gateOperators, " "))
+}
+
+func (g QualityGate) check(value float64) bool {
+	switch g.Operator {
+	case "<=":
+		return value <= g.Threshold
+	case ">=":
+		return value >= g.Threshold
+	case "==":
+		return value == g.Threshold
+	case "!=":
+		return value != g.Threshold
+	case "<":
+		return value < g.Threshold
+	case ">":
+		return value > g.Threshold
+	}
+	return false
+}
+
+// EvaluateQualityGates checks each gate against the current fields, or
+// against the difference with the first baseline for delta gates. A gate on
+// a field that does not exist fails.
+func EvaluateQualityGates(gates []QualityGate, fields map[string]interface{}, comparisons []BaselineComparison) []GateResult {
+	var results []GateResult
+	for _, gate := range gates {
+		result := GateResult{Gate: gate.Expression}
+
+		if deltaField, isDelta := strings.CutPrefix(gate.Field, DeltaGatePrefix); isDelta {
+			if len(comparisons) > 0 {
+				for _, diff := range comparisons[0].Diffs {
+					if diff.FieldName == deltaField {
+						result.Value, result.Found = diff.Difference, true
+					}
+				}
+			}
+		} else if value, exists := fields[gate.Field]; exists {
+			result.Value, result.Found = toFloat64(value)
+		}
+
+		if !result.Found {
+			result.Message = fmt.Sprintf("field %s not found", gate.Field)
+		} else {
+			result.Passed = gate.check(result.Value)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func GatesPassed(results []GateResult) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func ShowGateResults(results []GateResult) {
+	if len(results) == 0 {
+		return
+	}
+	maxGateLen := len("Quality Gate")
+	for _, result := range results {
+		maxGateLen = max(maxGateLen, len(result.Gate))
+	}
+	rowFormat := fmt.Sprintf("| %%-%ds | %%-12s | %%-6s |\n", maxGateLen)
+	separator := strings.Repeat("-", maxGateLen+28)
+
+	fmt.Println(separator)
+	fmt.Printf(rowFormat, "Quality Gate", "Value", "Result")
+	fmt.Println(separator)
+	for _, result := range results {
+		value, verdict := "-", "FAIL"
+		if result.Found {
+			value = strconv.FormatFloat(result.Value, 'f', -1, 64)
+		}
+		if result.Passed {
+			verdict = "PASS"
+		}
+		fmt.Printf(rowFormat, result.Gate, value, verdict)
+	}
+	fmt.Println(separator)
+}
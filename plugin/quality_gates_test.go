@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func TestParseQualityGates(t *testing.T) {
+	gates, err := ParseQualityGates("failed_tests<=0, line_covered_sum >= 100,delta.total_tests!=0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []QualityGate{
+		{Expression: "failed_tests<=0", Field: "failed_tests", Operator: "<=", Threshold: 0},
+		{Expression: "line_covered_sum >= 100", Field: "line_covered_sum", Operator: ">=", Threshold: 100},
+		{Expression: "delta.total_tests!=0", Field: "delta.total_tests", Operator: "!=", Threshold: 0},
+	}
+	if len(gates) != len(expected) {
+		t.Fatalf("Expected %d gates, got %+v", len(expected), gates)
+	}
+	for i := range expected {
+		if gates[i] != expected[i] {
+			t.Errorf("Expected gate %+v, got %+v", expected[i], gates[i])
+		}
+	}
+
+	for _, spec := range []string{"failed_tests", "failed_tests<=abc", "<=1"} {
+		if _, err := ParseQualityGates(spec); err == nil {
+			t.Errorf("Expected error for gate %q", spec)
+		}
+	}
+}
+
+func TestEvaluateQualityGates(t *testing.T) {
+	gates, _ := ParseQualityGates("failed_tests<=0,total_tests>10,delta.total_tests>=0,missing_field==1")
+	fields := map[string]interface{}{"failed_tests": 2, "total_tests": 12}
+	comparisons := []BaselineComparison{{
+		Diffs: ComputeResultDiffs(map[string]float64{"total_tests": 12}, map[string]float64{"total_tests": 10}),
+	}}
+
+	results := EvaluateQualityGates(gates, fields, comparisons)
+	expectedPassed := []bool{false, true, true, false}
+	for i, result := range results {
+		if result.Passed != expectedPassed[i] {
+			t.Errorf("Expected gate %s passed=%v, got %+v", result.Gate, expectedPassed[i], result)
+		}
+	}
+	if results[3].Found || results[3].Message == "" {
+		t.Errorf("Expected a missing field message, got %+v", results[3])
+	}
+	if GatesPassed(results) || !GatesPassed(results[1:3]) {
+		t.Errorf("Unexpected GatesPassed verdict")
+	}
+}
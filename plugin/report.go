@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// BuildReport collects everything known about the current build once the
+// results are aggregated, compared and trended, for the report outputs.
+type BuildReport struct {
+	Tool        string
+	Group       string
+	PipelineId  string
+	BuildId     string
+	Result      AggregateResult
+	Comparisons []BaselineComparison
+	Trend       *TrendReport
+	Gates       []GateResult
+}
+
+func NewBuildReport(args Args, result AggregateResult) BuildReport {
+	return BuildReport{
+		Tool:       result.Tool,
+		Group:      args.GroupName,
+		PipelineId: result.Tags["pipelineId"],
+		BuildId:    result.Tags["buildId"],
+		Result:     result,
+	}
+}
+
+// ExportReports writes the result document and the report files requested
+// in args, and exports their paths as output variables. The adaptive card is
+// written whenever DRONE_CARD_PATH is set; failing to write it only logs an
+// error.
+func ExportReports(args Args, report BuildReport) error {
+	resultsDataFile := args.ResultsDataFile
+	if resultsDataFile == "" {
+		resultsDataFile = DefaultResultsDataFile
+	}
+	err := WriteResultDocument(resultsDataFile, NewResultDocument(args.Pipeline, report, args.ResultsDataAllTests))
+	if err != nil {
+		logrus.Println("Unable to write result document ", err)
+		return err
+	}
+	err = WriteToEnvVariable(TestResultsDataFileOutputVar, resultsDataFile)
+	if err != nil {
+		logrus.Println("Unable to write result document path to env variable ", err)
+		return err
+	}
+
+	if args.HtmlReport || args.HtmlReportFile != "" {
+		htmlReportFile := args.HtmlReportFile
+		if htmlReportFile == "" {
+			htmlReportFile = DefaultHtmlReportFile
+		}
+		err := WriteHtmlReport(htmlReportFile, report)
+		if err != nil {
+			logrus.Println("Unable to write HTML report ", err)
+			return err
+		}
+		err = WriteToEnvVariable(TestResultsHtmlReportOutputVar, htmlReportFile)
+		if err != nil {
+			logrus.Println("Unable to write HTML report path to env variable ", err)
+			return err
+		}
+	}
+
+	if args.MarkdownReport || args.MarkdownReportFile != "" {
+		markdownReportFile := args.MarkdownReportFile
+		if markdownReportFile == "" {
+			markdownReportFile = DefaultMarkdownReportFile
+		}
+		err := WriteStrToFile(markdownReportFile, RenderMarkdownReport(report))
+		if err != nil {
+			logrus.Println("Unable to write Markdown report ", err)
+			return err
+		}
+		err = WriteToEnvVariable(TestResultsMarkdownReportOutputVar, markdownReportFile)
+		if err != nil {
+			logrus.Println("Unable to write Markdown report path to env variable ", err)
+			return err
+		}
+	}
+
+	if args.Card.Path != "" {
+		err := WriteCard(args.Card.Path, args.CardSchema, NewCardData(report))
+		if err != nil {
+			logrus.Println("Unable to write adaptive card ", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// DiscoveryReport describes how the report files were found.
+type DiscoveryReport struct {
+	ReportsDir string
+	Includes   map[string]int
+	Excludes   []string
+	Excluded   []string
+	Duplicates []string
+	Files      []string
+}
+
+// SplitPatterns splits a comma separated list of patterns, dropping the
+// empty ones.
+func SplitPatterns(patterns string) []string {
+	var result []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+// DiscoverReportFiles returns the files under reportsRootDir matching any of
+// the include patterns and none of the exclude patterns, relative to
+// reportsRootDir and in the order they were matched. A file matched by
+// several patterns, or through several symlinks, is returned once.
+func DiscoverReportFiles(reportsRootDir string, includes, excludes []string) ([]string, error) {
+	report := DiscoveryReport{ReportsDir: reportsRootDir, Includes: map[string]int{}}
+	for i, exclude := range excludes {
+		exclude = relativePattern(reportsRootDir, exclude)
+		if !doublestar.ValidatePattern(exclude) {
+			return nil, fmt.Errorf("invalid exclude pattern %s", excludes[i])
+		}
+		report.Excludes = append(report.Excludes, exclude)
+	}
+
+	seen := map[string]bool{}
+	reportsFS := os.DirFS(reportsRootDir)
+	for _, include := range includes {
+		filesList, err := doublestar.Glob(reportsFS, relativePattern(reportsRootDir, include))
+		if err != nil {
+			logrus.Println("Include patterns not found ", err.Error())
+			return nil, err
+		}
+		report.Includes[include] = len(filesList)
+
+		for _, file := range filesList {
+			if isExcluded(file, report.Excludes) {
+				report.Excluded = append(report.Excluded, file)
+				continue
+			}
+			realPath := filepath.Join(reportsRootDir, file)
+			if resolved, err := filepath.EvalSymlinks(realPath); err == nil {
+				realPath = resolved
+			}
+			if seen[realPath] {
+				report.Duplicates = append(report.Duplicates, file)
+				continue
+			}
+			seen[realPath] = true
+			report.Files = append(report.Files, file)
+		}
+	}
+
+	ShowDiscoveryReport(includes, report)
+	return report.Files, nil
+}
+
+// relativePattern makes a pattern relative to the reports directory. Patterns
+// may start with ~ or with the reports directory itself.
+func relativePattern(reportsRootDir, pattern string) string {
+	if expanded, err := expandTilde(pattern); err == nil {
+		pattern = expanded
+	}
+	return strings.TrimPrefix(pattern, strings.TrimSuffix(reportsRootDir, "/")+"/")
+}
+
+func isExcluded(file string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if matched, _ := doublestar.Match(exclude, file); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowDiscoveryReport prints the patterns, the number of files each one
+// matched and the files left out, at debug level.
+func ShowDiscoveryReport(includes []string, report DiscoveryReport) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	logrus.Debugf("Report discovery in %s", report.ReportsDir)
+	for _, include := range includes {
+		logrus.Debugf("  include %s matched %d files", include, report.Includes[include])
+	}
+	for _, exclude := range report.Excludes {
+		logrus.Debugf("  exclude %s", exclude)
+	}
+	for _, file := range report.Excluded {
+		logrus.Debugf("  excluded %s", file)
+	}
+	for _, file := range report.Duplicates {
+		logrus.Debugf("  duplicate %s", file)
+	}
+	logrus.Debugf("  %d report files found", len(report.Files))
+}
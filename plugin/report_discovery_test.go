@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func writeDiscoveryTree(t *testing.T) string {
+	reportsDir := t.TempDir()
+	for _, file := range []string{
+		"api/target/surefire-reports/TEST-a.xml",
+		"api/target/surefire-reports/TEST-b.xml",
+		"api/target/generated/TEST-generated.xml",
+		"web/target/surefire-reports/TEST-c.xml",
+	} {
+		path := filepath.Join(reportsDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(JunitReportXml), 0644); err != nil {
+			t.Fatalf("Error writing report: %v", err)
+		}
+	}
+	return reportsDir
+}
+
+func TestDiscoverReportFiles(t *testing.T) {
+	reportsDir := writeDiscoveryTree(t)
+	if err := os.Symlink(filepath.Join(reportsDir, "web"), filepath.Join(reportsDir, "web-link")); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+
+	files, err := DiscoverReportFiles(reportsDir,
+		SplitPatterns("**/TEST-*.xml, api/**/TEST-a.xml, web-link/target/surefire-reports/*.xml"), SplitPatterns(reportsDir+"/**/generated/**"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"api/target/surefire-reports/TEST-a.xml",
+		"api/target/surefire-reports/TEST-b.xml",
+		"web/target/surefire-reports/TEST-c.xml",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	if _, err := DiscoverReportFiles(reportsDir, []string{"**/*.xml"}, []string{"[a"}); err == nil {
+		t.Errorf("Expected error for an invalid exclude pattern")
+	}
+}
+
+func TestDiscoveryReportAtDebugLevel(t *testing.T) {
+	reportsDir := writeDiscoveryTree(t)
+	var output bytes.Buffer
+	logrus.SetOutput(&output)
+	logrus.SetLevel(logrus.DebugLevel)
+	defer func() {
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(logrus.InfoLevel)
+	}()
+
+	if _, err := DiscoverReportFiles(reportsDir, []string{"**/TEST-*.xml"}, []string{"**/generated/**"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"include **/TEST-*.xml matched 4 files",
+		"exclude **/generated/**",
+		"excluded api/target/generated/TEST-generated.xml",
+		"3 report files found",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("Expected %q in the discovery report, got %s", line, output.String())
+		}
+	}
+}
+
+func TestGetFilesUsesDiscovery(t *testing.T) {
+	reportsDir := writeDiscoveryTree(t)
+	files := getFiles([]string{reportsDir + "/web/**/*.xml", reportsDir + "/api/target/surefire-reports/TEST-a.xml"}, logrus.New())
+	expected := []string{
+		filepath.Join(reportsDir, "web/target/surefire-reports/TEST-c.xml"),
+		filepath.Join(reportsDir, "api/target/surefire-reports/TEST-a.xml"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestAggregateExcludePattern(t *testing.T) {
+	reportsDir := writeDiscoveryTree(t)
+	t.Setenv("HARNESS_PIPELINE_ID", "pipeline")
+	t.Setenv("HARNESS_BUILD_ID", "1")
+	t.Setenv("DRONE_OUTPUT", filepath.Join(t.TempDir(), "output.env"))
+
+	options := ParseOptions{Mode: LenientParseMode, ExcludePattern: "web/**"}
+	result, _ := GetNewJunitAggregator(reportsDir, "", "**/TEST-*.xml", options).Aggregate()
+	if len(result.Files) != 3 {
+		t.Errorf("Expected 3 report files, got %d", len(result.Files))
+	}
+	for _, file := range result.Files {
+		if strings.HasPrefix(file.Path, "web/") {
+			t.Errorf("Expected %s to be excluded", file.Path)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package plugin
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultArchivePattern selects the reports inside matched archives.
+	DefaultArchivePattern = "**/*.xml"
+	// ArchiveEntrySeparator separates the archive from the entry in the path
+	// of a report read from an archive, as in reports.zip!TEST-a.xml.
+	ArchiveEntrySeparator = "!"
+)
+
+// MaxArchiveEntrySize caps the size of a report read from an archive. Larger
+// entries are skipped rather than extracted or parsed.
+var MaxArchiveEntrySize int64 = 512 << 20
+
+var errArchiveEntryTooLarge = errors.New("archive entry too large")
+
+func archiveEntryTooLarge(path string) SkippedFile {
+	return SkippedFile{Path: path, Reason: fmt.Sprintf("archive entry larger than %d bytes", MaxArchiveEntrySize)}
+}
+
+// ReportSource is a report to parse: a file, a gzipped file or an entry of
+// an archive.
+type ReportSource struct {
+	Path string
+	open func() (io.ReadCloser, error)
+}
+
+func (s ReportSource) Open() (io.ReadCloser, error) {
+	return s.open()
+}
+
+// multiCloser closes the decompressor and the file it reads from.
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m multiCloser) Close() error {
+	var errs []error
+	for _, closer := range m.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func fileSource(path, filename string) ReportSource {
+	return ReportSource{Path: path, open: func() (io.ReadCloser, error) {
+		return os.Open(filename)
+	}}
+}
+
+func gzipSource(path, filename string) ReportSource {
+	return ReportSource{Path: path, open: func() (io.ReadCloser, error) {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		return multiCloser{Reader: reader, closers: []io.Closer{reader, file}}, nil
+	}}
+}
+
+// ExpandReportSources turns the matched files, relative to root, into the
+// reports to parse. .xml.gz files are decompressed while parsing, and the
+// entries of .zip, .tar.gz and .tgz archives matching innerPattern are read
+// as reports of their own. Tar entries are extracted to a temporary
+// directory, removed by the returned cleanup function. Archives that cannot
+// be read and entries larger than MaxArchiveEntrySize are returned as
+// skipped.
+func ExpandReportSources(root string, files []string, innerPattern string) ([]ReportSource, []SkippedFile, func()) {
+	if innerPattern == "" {
+		innerPattern = DefaultArchivePattern
+	}
+
+	var sources []ReportSource
+	var skipped []SkippedFile
+	var tempDirs []string
+	cleanup := func() {
+		for _, dir := range tempDirs {
+			os.RemoveAll(dir)
+		}
+	}
+
+	for _, file := range files {
+		filename := filepath.Join(root, file)
+		lowerFile := strings.ToLower(file)
+
+		switch {
+		case strings.HasSuffix(lowerFile, ".zip"):
+			entries, skippedEntries, err := zipSources(file, filename, innerPattern)
+			if err != nil {
+				skipped = append(skipped, SkippedFile{Path: file, Reason: err.Error()})
+				continue
+			}
+			sources = append(sources, entries...)
+			skipped = append(skipped, skippedEntries...)
+		case strings.HasSuffix(lowerFile, ".tar.gz"), strings.HasSuffix(lowerFile, ".tgz"):
+			tempDir, err := os.MkdirTemp("", "test-results-archive-")
+			if err != nil {
+				skipped = append(skipped, SkippedFile{Path: file, Reason: err.Error()})
+				continue
+			}
+			tempDirs = append(tempDirs, tempDir)
+			entries, skippedEntries, err := tarSources(file, filename, innerPattern, tempDir)
+			if err != nil {
+				skipped = append(skipped, SkippedFile{Path: file, Reason: err.Error()})
+				continue
+			}
+			sources = append(sources, entries...)
+			skipped = append(skipped, skippedEntries...)
+		case strings.HasSuffix(lowerFile, ".gz"):
+			sources = append(sources, gzipSource(file, filename))
+		default:
+			sources = append(sources, fileSource(file, filename))
+		}
+	}
+	return sources, skipped, cleanup
+}
+
+func zipSources(path, filename, innerPattern string) ([]ReportSource, []SkippedFile, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	defer archive.Close()
+
+	var sources []ReportSource
+	var skipped []SkippedFile
+	for index, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !matchArchiveEntry(innerPattern, entry.Name) {
+			continue
+		}
+		entryPath := path + ArchiveEntrySeparator + entry.Name
+		if entry.UncompressedSize64 > uint64(MaxArchiveEntrySize) {
+			logrus.Println("Skipping archive entry ", entryPath, " of ", entry.UncompressedSize64, " bytes")
+			skipped = append(skipped, archiveEntryTooLarge(entryPath))
+			continue
+		}
+		index := index
+		source := ReportSource{Path: entryPath, open: func() (io.ReadCloser, error) {
+			archive, err := zip.OpenReader(filename)
+			if err != nil {
+				return nil, err
+			}
+			reader, err := archive.File[index].Open()
+			if err != nil {
+				archive.Close()
+				return nil, err
+			}
+			// the declared size is checked above, the limit guards
+			// against entries that hold more than they declare
+			limited := io.LimitReader(reader, MaxArchiveEntrySize)
+			return multiCloser{Reader: limited, closers: []io.Closer{reader, archive}}, nil
+		}}
+		if strings.HasSuffix(strings.ToLower(entry.Name), ".gz") {
+			source = gzipEntrySource(source)
+		}
+		sources = append(sources, source)
+	}
+	logrus.Printf("Found %d reports matching %s in %s", len(sources), innerPattern, path)
+	return sources, skipped, nil
+}
+
+func tarSources(path, filename, innerPattern, tempDir string) ([]ReportSource, []SkippedFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gzip file: %w", err)
+	}
+	defer gzipReader.Close()
+
+	var sources []ReportSource
+	var skipped []SkippedFile
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !matchArchiveEntry(innerPattern, header.Name) {
+			continue
+		}
+		entryPath := path + ArchiveEntrySeparator + header.Name
+
+		// entries are extracted under a generated name so that their
+		// names cannot point outside of tempDir
+		extracted := filepath.Join(tempDir, fmt.Sprintf("%d%s", len(sources), filepath.Ext(header.Name)))
+		err = extractTarEntry(tarReader, extracted)
+		if errors.Is(err, errArchiveEntryTooLarge) {
+			logrus.Println("Skipping archive entry ", entryPath, " of ", header.Size, " bytes")
+			skipped = append(skipped, archiveEntryTooLarge(entryPath))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		source := fileSource(entryPath, extracted)
+		if strings.HasSuffix(strings.ToLower(header.Name), ".gz") {
+			source = gzipSource(source.Path, extracted)
+		}
+		sources = append(sources, source)
+	}
+	logrus.Printf("Found %d reports matching %s in %s", len(sources), innerPattern, path)
+	return sources, skipped, nil
+}
+
+// extractTarEntry writes at most MaxArchiveEntrySize bytes of the entry to
+// filename, and removes it again when the entry is larger.
+func extractTarEntry(reader io.Reader, filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(out, io.LimitReader(reader, MaxArchiveEntrySize+1))
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("invalid tar archive: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if written > MaxArchiveEntrySize {
+		os.Remove(filename)
+		return errArchiveEntryTooLarge
+	}
+	return nil
+}
+
+// gzipEntrySource decompresses a gzipped entry of a zip archive.
+func gzipEntrySource(source ReportSource) ReportSource {
+	return ReportSource{Path: source.Path, open: func() (io.ReadCloser, error) {
+		entry, err := source.Open()
+		if err != nil {
+			return nil, err
+		}
+		reader, err := gzip.NewReader(entry)
+		if err != nil {
+			entry.Close()
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		return multiCloser{Reader: reader, closers: []io.Closer{reader, entry}}, nil
+	}}
+}
+
+func matchArchiveEntry(pattern, name string) bool {
+	matched, err := doublestar.Match(pattern, strings.TrimPrefix(name, "./"))
+	return err == nil && matched
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const archivedNunitXml = `<?xml version="1.0" encoding="utf-8"?>
+<test-run id="0" total="4" passed="3" failed="1" skipped="0" result="Failed"></test-run>`
+
+func writeZipArchive(t *testing.T, path string, entries map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Error creating archive: %v", err)
+	}
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	for name, content := range entries {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Error adding %s: %v", name, err)
+		}
+		io.WriteString(entry, content)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Error writing archive: %v", err)
+	}
+}
+
+func writeTarGzArchive(t *testing.T, path string, entries map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Error creating archive: %v", err)
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	writer := tar.NewWriter(gzipWriter)
+	for name, content := range entries {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("Error adding %s: %v", name, err)
+		}
+		io.WriteString(writer, content)
+	}
+	writer.Close()
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("Error writing archive: %v", err)
+	}
+}
+
+func writeGzipFile(t *testing.T, path, content string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Error creating file: %v", err)
+	}
+	defer file.Close()
+	writer := gzip.NewWriter(file)
+	io.WriteString(writer, content)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+}
+
+func TestGetXmlReportDataReadsArchives(t *testing.T) {
+	reportsDir := t.TempDir()
+	writeGzipFile(t, filepath.Join(reportsDir, "TestResult.xml.gz"), archivedNunitXml)
+	writeZipArchive(t, filepath.Join(reportsDir, "reports.zip"), map[string]string{
+		"nunit/TestResult.xml": archivedNunitXml,
+		"README.txt":           "not a report",
+	})
+	writeTarGzArchive(t, filepath.Join(reportsDir, "reports.tar.gz"), map[string]string{
+		"nunit/TestResult.xml": archivedNunitXml,
+	})
+	os.WriteFile(filepath.Join(reportsDir, "broken.zip"), []byte("not a zip"), 0644)
+
+	options := ParseOptions{Mode: LenientParseMode, ArchivePattern: "nunit/*.xml"}
+	reports, paths, skipped, err := GetXmlReportData[TestRunSummary](reportsDir,
+		[]string{"*.xml.gz", "*.zip", "*.tar.gz"}, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedPaths := []string{"TestResult.xml.gz", "reports.zip!nunit/TestResult.xml", "reports.tar.gz!nunit/TestResult.xml"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected reports %v, got %v", expectedPaths, paths)
+	}
+	for i, report := range reports {
+		if report.TotalCases != 4 || report.TotalFailed != 1 {
+			t.Errorf("Expected 4 cases with 1 failure in %s, got %+v", paths[i], report)
+		}
+	}
+	if len(skipped) != 1 || skipped[0].Path != "broken.zip" {
+		t.Errorf("Expected broken.zip to be skipped, got %+v", skipped)
+	}
+}
+
+func TestParseTestsReadsArchives(t *testing.T) {
+	reportsDir := t.TempDir()
+	writeZipArchive(t, filepath.Join(reportsDir, "surefire.zip"), map[string]string{
+		"TEST-a.xml": JunitReportXml,
+		"TEST-b.xml": JunitReportXml,
+	})
+	writeGzipFile(t, filepath.Join(reportsDir, "TEST-c.xml.gz"), JunitReportXml)
+
+	paths := []string{filepath.Join(reportsDir, "surefire.zip"), filepath.Join(reportsDir, "TEST-c.xml.gz")}
+	stats, _ := ParseTests(paths, ParseOptions{ArchivePattern: DefaultArchivePattern}, logrus.New())
+	if len(stats.Files) != 3 || stats.TestCount != 3*5 {
+		t.Errorf("Expected 3 reports with 15 tests, got %d reports with %d tests", len(stats.Files), stats.TestCount)
+	}
+	if len(stats.SkippedFiles) != 0 {
+		t.Errorf("Expected no skipped files, got %+v", stats.SkippedFiles)
+	}
+}
+
+func TestExpandReportSourcesSkipsLargeEntries(t *testing.T) {
+	defer func(limit int64) { MaxArchiveEntrySize = limit }(MaxArchiveEntrySize)
+	MaxArchiveEntrySize = int64(len(archivedNunitXml))
+
+	reportsDir := t.TempDir()
+	entries := map[string]string{
+		"small.xml": archivedNunitXml,
+		"large.xml": archivedNunitXml + "<!-- padding -->",
+	}
+	writeZipArchive(t, filepath.Join(reportsDir, "reports.zip"), entries)
+	writeTarGzArchive(t, filepath.Join(reportsDir, "reports.tgz"), entries)
+
+	sources, skipped, cleanup := ExpandReportSources(reportsDir, []string{"reports.zip", "reports.tgz"}, "")
+	defer cleanup()
+
+	var paths []string
+	for _, source := range sources {
+		paths = append(paths, source.Path)
+	}
+	expectedPaths := []string{"reports.zip!small.xml", "reports.tgz!small.xml"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected reports %v, got %v", expectedPaths, paths)
+	}
+	expectedSkipped := []string{"reports.zip!large.xml", "reports.tgz!large.xml"}
+	if len(skipped) != 2 {
+		t.Fatalf("Expected the large entries to be skipped, got %+v", skipped)
+	}
+	for i, skippedFile := range skipped {
+		if skippedFile.Path != expectedSkipped[i] || !strings.Contains(skippedFile.Reason, "archive entry larger than") {
+			t.Errorf("Expected %s to be skipped as too large, got %+v", expectedSkipped[i], skippedFile)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamedJacocoXml = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<report name="app">
+    <sessioninfo id="s1" start="1" dump="2"/>
+    <group name="ignored">
+        <package name="com/grouped"><counter type="LINE" missed="100" covered="100"/></package>
+    </group>
+    <package name="com/a">
+        <class name="com/a/A" sourcefilename="A.java">
+            <method name="run" desc="()V" line="3"><counter type="LINE" missed="7" covered="7"/></method>
+            <counter type="LINE" missed="7" covered="7"/>
+        </class>
+        <sourcefile name="A.java">
+            <line nr="3" mi="0" ci="3" mb="0" cb="0"/>
+            <counter type="LINE" missed="7" covered="7"/>
+        </sourcefile>
+        <counter type="LINE" missed="1" covered="9"/>
+    </package>
+    <counter type="LINE" missed="1" covered="9"/>
+</report>`
+
+func writeReport(t testing.TB, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing report: %v", err)
+	}
+	return path
+}
+
+func TestParseXmlReportStreamsJacoco(t *testing.T) {
+	path := writeReport(t, t.TempDir(), "jacoco.xml", streamedJacocoXml)
+
+	report, err := ParseXmlReport[Report](path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Counters) != 1 || report.Counters[0].Covered != 9 {
+		t.Errorf("Expected only the report counter, got %+v", report.Counters)
+	}
+	if len(report.Packages) != 1 || report.Packages[0].Name != "com/a" ||
+		len(report.Packages[0].Counters) != 1 || report.Packages[0].Counters[0].Missed != 1 {
+		t.Errorf("Expected only the package counters of com/a, got %+v", report.Packages)
+	}
+
+	path = writeReport(t, t.TempDir(), "TEST-a.xml", JunitReportXml)
+	if _, err := ParseXmlReport[Report](path); err == nil || !strings.Contains(err.Error(), "expected element type <report>") {
+		t.Errorf("Expected an error for a report of another tool, got %v", err)
+	}
+}
+
+const streamedTestNgXml = `<?xml version="1.0" encoding="UTF-8"?>
+<testng-results ignored="0" total="3" passed="1" failed="1" skipped="1">
+  <reporter-output><line>ignored</line></reporter-output>
+  <suite name="Suite" duration-ms="30">
+    <groups><group name="smoke"><method name="login" class="com.example.LoginTest"/></group></groups>
+    <test name="Test">
+      <class name="com.example.LoginTest">
+        <test-method status="PASS" name="login" duration-ms="10"/>
+        <test-method status="FAIL" name="logout" duration-ms="15">
+          <exception class="java.lang.AssertionError">
+            <message>expected true</message>
+            <full-stacktrace>java.lang.AssertionError: expected true at LoginTest.logout</full-stacktrace>
+            <short-stacktrace> java.lang.AssertionError: expected true </short-stacktrace>
+          </exception>
+        </test-method>
+        <test-method status="SKIP" name="reset" duration-ms="5"/>
+      </class>
+    </test>
+  </suite>
+</testng-results>`
+
+func TestParseXmlReportStreamsTestNg(t *testing.T) {
+	path := writeReport(t, t.TempDir(), "testng-results.xml", streamedTestNgXml)
+
+	report, err := ParseXmlReport[TestNGReport](path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Results{Total: 3, Failures: 1, Skipped: 1, DurationMS: 30}
+	if report.AggregatedResults != expected {
+		t.Errorf("Expected %+v, got %+v", expected, report.AggregatedResults)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].ClassName != "com.example.LoginTest" ||
+		report.Failures[0].Name != "logout" || report.Failures[0].Message != "java.lang.AssertionError: expected true" {
+		t.Errorf("Unexpected failures: %+v", report.Failures)
+	}
+	if len(report.Suites) != 1 || report.Suites[0].Name != "Suite" || report.Suites[0].Duration != "30" {
+		t.Errorf("Unexpected suites: %+v", report.Suites)
+	}
+	expectedGroups := []Group{{Name: "smoke", Methods: []Method{{Name: "login", ClassName: "com.example.LoginTest"}}}}
+	if !reflect.DeepEqual(report.Suites[0].Groups, expectedGroups) || report.Suites[0].Classes != nil {
+		t.Errorf("Expected the suite groups without the streamed classes, got %+v", report.Suites[0])
+	}
+
+	suiteXml := streamedTestNgXml[strings.Index(streamedTestNgXml, "<suite"):strings.Index(streamedTestNgXml, "</testng-results>")]
+	var suite Suite
+	if err := xml.Unmarshal([]byte(suiteXml), &suite); err != nil || len(suite.Classes) != 1 || len(suite.Classes[0].Tests) != 3 {
+		t.Errorf("Expected a decoded suite to keep its classes, got %+v (%v)", suite, err)
+	}
+
+	truncated := writeReport(t, t.TempDir(), "truncated.xml", streamedTestNgXml[:len(streamedTestNgXml)/2])
+	if _, err := ParseXmlReport[TestNGReport](truncated); err == nil {
+		t.Errorf("Expected an error for a truncated report")
+	}
+}
+
+func TestParseXmlReportLargeTestNg(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`<testng-results><suite name="large"><test name="t">`)
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&sb, `<class name="C%d">`, i)
+		for j := 0; j < 10; j++ {
+			status := "PASS"
+			if j == 0 {
+				status = "FAIL"
+			}
+			fmt.Fprintf(&sb, `<test-method name="m%d" status="%s" duration-ms="1"><exception class="E"><full-stacktrace>%s</full-stacktrace><short-stacktrace>boom</short-stacktrace></exception></test-method>`,
+				j, status, strings.Repeat("at x\n", 20))
+		}
+		sb.WriteString(`</class>`)
+	}
+	sb.WriteString(`</test></suite></testng-results>`)
+	path := writeReport(t, t.TempDir(), "testng-results.xml", sb.String())
+
+	report, err := ParseXmlReport[TestNGReport](path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.AggregatedResults.Total != 20000 || report.AggregatedResults.Failures != 2000 ||
+		report.AggregatedResults.DurationMS != 20000 || len(report.Failures) != 2000 || report.Failures[0].Message != "boom" {
+		t.Errorf("Unexpected aggregate: %+v with %d failures", report.AggregatedResults, len(report.Failures))
+	}
+}
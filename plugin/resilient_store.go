@@ -0,0 +1,330 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	lp "github.com/influxdata/line-protocol"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultStoreTimeout      = 30 * time.Second
+	DefaultStoreAttempts     = 3
+	DefaultStoreRetryBackoff = time.Second
+	MaxStoreRetryBackoff     = 30 * time.Second
+	// RejectedSpoolSuffix is appended to the spool file name for the points
+	// the result store rejected on replay.
+	RejectedSpoolSuffix = ".rejected"
+)
+
+// StoreOptions bound every result store call by Timeout and retry it up to
+// Attempts times, waiting RetryBackoff before the first retry and twice as
+// long before each next one. Points that cannot be written are appended to
+// SpoolFile when it is set.
+type StoreOptions struct {
+	Timeout      time.Duration
+	Attempts     int
+	RetryBackoff time.Duration
+	SpoolFile    string
+}
+
+func GetStoreOptions(args Args) (StoreOptions, error) {
+	options := StoreOptions{
+		Timeout:      DefaultStoreTimeout,
+		Attempts:     DefaultStoreAttempts,
+		RetryBackoff: DefaultStoreRetryBackoff,
+		SpoolFile:    args.StoreSpoolFile,
+	}
+	if args.StoreAttempts < 0 {
+		return options, fmt.Errorf("store attempts must not be negative, got %d", args.StoreAttempts)
+	}
+	if args.StoreAttempts > 0 {
+		options.Attempts = args.StoreAttempts
+	}
+	if args.StoreTimeout != "" {
+		timeout, err := time.ParseDuration(args.StoreTimeout)
+		if err != nil || timeout <= 0 {
+			return options, fmt.Errorf("invalid store timeout %s", args.StoreTimeout)
+		}
+		options.Timeout = timeout
+	}
+	if args.StoreRetryBackoff != "" {
+		backoff, err := time.ParseDuration(args.StoreRetryBackoff)
+		if err != nil || backoff < 0 {
+			return options, fmt.Errorf("invalid store retry backoff %s", args.StoreRetryBackoff)
+		}
+		options.RetryBackoff = backoff
+	}
+	return options, nil
+}
+
+// ResilientStore wraps a result store with timeouts and retries of transient
+// errors. Once a call has failed all its attempts the store is considered
+// unreachable and later calls fail at once, so a database outage costs the
+// retries only once. Points that cannot be written because the store is
+// unreachable are spooled and replayed by ReplaySpool on the next run.
+type ResilientStore struct {
+	ResultStore
+	Options     StoreOptions
+	unreachable error
+	sleep       func(ctx context.Context, delay time.Duration) error
+}
+
+func NewResilientStore(store ResultStore, options StoreOptions) *ResilientStore {
+	return &ResilientStore{ResultStore: store, Options: options, sleep: sleepContext}
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retry runs call with a timeout per attempt until it succeeds, fails with a
+// permanent error, the attempts are used up or ctx is done.
+func (s *ResilientStore) retry(ctx context.Context, name string, call func(ctx context.Context) error) error {
+	if s.unreachable != nil {
+		return fmt.Errorf("result store unreachable, skipping %s: %w", name, s.unreachable)
+	}
+
+	backoff := s.Options.RetryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.Options.Timeout)
+		err = call(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !isTransientStoreError(err) {
+			// the store is reachable but rejected the call
+			return err
+		}
+		if ctx.Err() != nil || attempt >= max(s.Options.Attempts, 1) {
+			break
+		}
+		logrus.Printf("Result store %s failed (attempt %d of %d), retrying in %s: %v", name, attempt, s.Options.Attempts, backoff, err)
+		if sleepErr := s.sleep(ctx, backoff); sleepErr != nil {
+			break
+		}
+		backoff = min(backoff*2, MaxStoreRetryBackoff)
+	}
+
+	if ctx.Err() == nil {
+		s.unreachable = err
+	}
+	return err
+}
+
+func (s *ResilientStore) WritePoint(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}) error {
+	err := s.retry(ctx, "write", func(ctx context.Context) error {
+		return s.ResultStore.WritePoint(ctx, measurement, tags, fields)
+	})
+	if err == nil || s.Options.SpoolFile == "" || !isTransientStoreError(err) {
+		return err
+	}
+
+	spool := NewLineProtocolFileStore(s.Options.SpoolFile, nil)
+	if spoolErr := spool.WritePoint(ctx, measurement, tags, fields); spoolErr != nil {
+		return fmt.Errorf("%w, and spooling the point failed: %w", err, spoolErr)
+	}
+	logrus.Warnln("Result store unreachable, point spooled to ", s.Options.SpoolFile, " for the next run: ", err)
+	return nil
+}
+
+func (s *ResilientStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	var values map[string]float64
+	err := s.retry(ctx, "query", func(ctx context.Context) error {
+		var err error
+		values, err = s.ResultStore.FetchBuild(ctx, query, buildId)
+		return err
+	})
+	return values, err
+}
+
+func (s *ResilientStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	var records []BuildRecord
+	err := s.retry(ctx, "query", func(ctx context.Context) error {
+		var err error
+		records, err = s.ResultStore.ListBuilds(ctx, query)
+		return err
+	})
+	return records, err
+}
+
+// isTransientStoreError tells whether a failed store call may succeed when
+// retried: timeouts, connection errors, 408, 429 and 5xx responses. Other
+// errors, such as a rejected point or invalid credentials, are permanent.
+func isTransientStoreError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return isTransientStatus(statusErr.StatusCode)
+	}
+	var influxErr *influxhttp.Error
+	if errors.As(err, &influxErr) && influxErr.StatusCode != 0 {
+		return isTransientStatus(influxErr.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isTransientStatus(statusCode int) bool {
+	return statusCode == 408 || statusCode == 429 || statusCode >= 500
+}
+
+// writePointAt writes a point with its own time when the store supports it.
+func (s *ResilientStore) writePointAt(ctx context.Context, measurement string,
+	tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+
+	if timed, ok := s.ResultStore.(TimedPointWriter); ok {
+		return timed.WritePointAt(ctx, measurement, tags, fields, pointTime)
+	}
+	return s.ResultStore.WritePoint(ctx, measurement, tags, fields)
+}
+
+// ReplaySpool writes the points spooled by earlier runs, oldest first and with
+// the time they were spooled at. Replay stops when the store is unreachable,
+// and the points not written yet stay in the spool file for the next run.
+// Points the store rejects are moved to the spool file name with
+// RejectedSpoolSuffix, and lines that are not valid line protocol are dropped.
+func (s *ResilientStore) ReplaySpool(ctx context.Context) error {
+	if s.Options.SpoolFile == "" {
+		return nil
+	}
+	lines, err := readSpoolLines(s.Options.SpoolFile)
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+
+	logrus.Println("Replaying ", len(lines), " spooled points from ", s.Options.SpoolFile)
+	rejectedFile := s.Options.SpoolFile + RejectedSpoolSuffix
+	var rejected []string
+	replayed := 0
+	for i, line := range lines {
+		metric, err := lp.NewStreamParser(strings.NewReader(line)).Next()
+		if err != nil {
+			logrus.Warnln("Dropping invalid spooled point: ", err)
+			continue
+		}
+		tags := map[string]string{}
+		for _, tag := range metric.TagList() {
+			tags[tag.Key] = tag.Value
+		}
+		fields := map[string]interface{}{}
+		for _, field := range metric.FieldList() {
+			fields[field.Key] = field.Value
+		}
+
+		err = s.retry(ctx, "write", func(ctx context.Context) error {
+			return s.writePointAt(ctx, metric.Name(), tags, fields, metric.Time())
+		})
+		if err == nil {
+			replayed++
+			continue
+		}
+		if !isTransientStoreError(err) {
+			logrus.Warnln("Result store rejected spooled point, moving it to ", rejectedFile, ": ", err)
+			rejected = append(rejected, line)
+			continue
+		}
+		if rejectErr := appendSpoolLines(rejectedFile, rejected); rejectErr != nil {
+			return rejectErr
+		}
+		if writeErr := writeSpoolLines(s.Options.SpoolFile, lines[i:]); writeErr != nil {
+			return writeErr
+		}
+		return fmt.Errorf("%d of %d spooled points replayed: %w", replayed, len(lines), err)
+	}
+	logrus.Println("Replayed ", replayed, " of ", len(lines), " spooled points")
+	if err := appendSpoolLines(rejectedFile, rejected); err != nil {
+		return err
+	}
+	return writeSpoolLines(s.Options.SpoolFile, nil)
+}
+
+func readSpoolLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spool file: %w", err)
+	}
+	return lines, nil
+}
+
+// writeSpoolLines replaces the spool file with the lines, and removes it when
+// there are none.
+func writeSpoolLines(path string, lines []string) error {
+	if len(lines) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool file: %w", err)
+		}
+		return nil
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	return nil
+}
+
+func appendSpoolLines(path string, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open rejected spool file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to write rejected spool file: %w", err)
+	}
+	return nil
+}
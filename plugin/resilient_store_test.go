@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+)
+
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+// flakyStore fails its first failures calls with err, or a connection error,
+// and blocks until the context is done when block is set.
+type flakyStore struct {
+	failures int
+	err      error
+	block    bool
+	calls    int
+	points   []map[string]interface{}
+}
+
+func (f *flakyStore) call(ctx context.Context) error {
+	f.calls++
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if f.calls <= f.failures && f.err != nil {
+		return f.err
+	}
+	if f.calls <= f.failures {
+		return errConnectionRefused
+	}
+	return nil
+}
+
+func (f *flakyStore) WritePoint(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}) error {
+	return f.WritePointAt(ctx, measurement, tags, fields, time.Now())
+}
+
+func (f *flakyStore) WritePointAt(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+	if err := f.call(ctx); err != nil {
+		return err
+	}
+	point := map[string]interface{}{"measurement": measurement, "buildId": tags["buildId"], "time": pointTime}
+	for key, value := range fields {
+		point[key] = value
+	}
+	f.points = append(f.points, point)
+	return nil
+}
+
+func (f *flakyStore) FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error) {
+	return map[string]float64{}, f.call(ctx)
+}
+
+func (f *flakyStore) ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error) {
+	return nil, f.call(ctx)
+}
+
+func (f *flakyStore) Close() {}
+
+func newTestResilientStore(upstream ResultStore, options StoreOptions) (*ResilientStore, *[]time.Duration) {
+	var delays []time.Duration
+	store := NewResilientStore(upstream, options)
+	store.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	return store, &delays
+}
+
+func TestResilientStoreRetriesWithBackoff(t *testing.T) {
+	upstream := &flakyStore{failures: 2}
+	store, delays := newTestResilientStore(upstream, StoreOptions{Timeout: time.Second, Attempts: 3, RetryBackoff: time.Second})
+
+	if _, err := store.FetchBuild(context.Background(), BuildQuery{}, "1"); err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if upstream.calls != 3 || !reflect.DeepEqual(*delays, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("Expected 3 calls with 1s and 2s backoff, got %d calls and %v", upstream.calls, *delays)
+	}
+}
+
+func TestResilientStoreFailsFastOnceUnreachable(t *testing.T) {
+	upstream := &flakyStore{failures: 100}
+	store, _ := newTestResilientStore(upstream, StoreOptions{Timeout: time.Second, Attempts: 2})
+
+	if _, err := store.ListBuilds(context.Background(), BuildQuery{}); err == nil {
+		t.Fatalf("Expected an error once the attempts are used up")
+	}
+	_, err := store.ListBuilds(context.Background(), BuildQuery{})
+	if err == nil || !strings.Contains(err.Error(), "result store unreachable") || upstream.calls != 2 {
+		t.Errorf("Expected the second call to fail without calling the store, got %v after %d calls", err, upstream.calls)
+	}
+}
+
+func TestResilientStoreDoesNotRetryRejectedCalls(t *testing.T) {
+	rejected := &httpStatusError{StatusCode: 400, Status: "400 Bad Request", Body: "field type conflict"}
+	upstream := &flakyStore{failures: 1, err: rejected}
+	store, delays := newTestResilientStore(upstream, StoreOptions{Timeout: time.Second, Attempts: 3, SpoolFile: filepath.Join(t.TempDir(), "spool.lp")})
+
+	err := store.WritePoint(context.Background(), JunitTool, map[string]string{"buildId": "1"}, map[string]interface{}{"total_tests": 5})
+	if !errors.Is(err, rejected) || upstream.calls != 1 || len(*delays) != 0 {
+		t.Fatalf("Expected the rejected write to fail without retries, got %v after %d calls", err, upstream.calls)
+	}
+	if _, err := os.Stat(store.Options.SpoolFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the rejected point not to be spooled, got %v", err)
+	}
+	if err := store.WritePoint(context.Background(), JunitTool, map[string]string{"buildId": "2"}, map[string]interface{}{"total_tests": 5}); err != nil {
+		t.Errorf("Expected the store to stay reachable after a rejected write, got %v", err)
+	}
+}
+
+func TestIsTransientStoreError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{errConnectionRefused, true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{&httpStatusError{StatusCode: 503}, true},
+		{&httpStatusError{StatusCode: 429}, true},
+		{&httpStatusError{StatusCode: 400}, false},
+		{&httpStatusError{StatusCode: 401}, false},
+		{&influxhttp.Error{StatusCode: 502}, true},
+		{&influxhttp.Error{StatusCode: 422}, false},
+		{influxhttp.NewError(errConnectionRefused), true},
+		{errors.New("failed to decode InfluxDB query response"), false},
+	}
+	for _, test := range tests {
+		if transient := isTransientStoreError(test.err); transient != test.transient {
+			t.Errorf("Expected transient %v for %v, got %v", test.transient, test.err, transient)
+		}
+	}
+}
+
+func TestResilientStoreTimeout(t *testing.T) {
+	upstream := &flakyStore{block: true}
+	store, _ := newTestResilientStore(upstream, StoreOptions{Timeout: 10 * time.Millisecond, Attempts: 1})
+
+	start := time.Now()
+	_, err := store.FetchBuild(context.Background(), BuildQuery{}, "1")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Expected the call to time out, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestResilientStoreSpoolsAndReplays(t *testing.T) {
+	spoolFile := filepath.Join(t.TempDir(), "spool.lp")
+	options := StoreOptions{Timeout: time.Second, Attempts: 1, SpoolFile: spoolFile}
+	tags := map[string]string{"pipelineId": "pipeline", "group": "suite_01"}
+
+	unreachable, _ := newTestResilientStore(&flakyStore{failures: 100}, options)
+	for _, buildId := range []string{"1", "2"} {
+		tags["buildId"] = buildId
+		err := unreachable.WritePoint(context.Background(), JunitTool, tags, map[string]interface{}{"total_tests": 5, PassRateField: 80.0})
+		if err != nil {
+			t.Fatalf("Expected the point to be spooled, got %v", err)
+		}
+	}
+
+	spooledAt := time.Now()
+
+	// the first replay writes one point before the store fails again
+	partial := &flakyStore{failures: 0}
+	partialStore, _ := newTestResilientStore(&failAfterStore{flakyStore: partial, successes: 1}, options)
+	if err := partialStore.ReplaySpool(context.Background()); err == nil {
+		t.Errorf("Expected the replay to stop at the failing point")
+	}
+	if lines, _ := readSpoolLines(spoolFile); len(lines) != 1 || !strings.Contains(lines[0], "buildId=2") {
+		t.Errorf("Expected the second point to stay spooled, got %v", lines)
+	}
+
+	upstream := &flakyStore{}
+	store, _ := newTestResilientStore(upstream, options)
+	if err := store.ReplaySpool(context.Background()); err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if len(upstream.points) != 1 || upstream.points[0]["time"].(time.Time).After(spooledAt) {
+		t.Fatalf("Expected the spooled point with the time it was spooled at, got %v", upstream.points)
+	}
+	delete(upstream.points[0], "time")
+	expected := []map[string]interface{}{
+		{"measurement": JunitTool, "buildId": "2", "total_tests": int64(5), PassRateField: 80.0},
+	}
+	if !reflect.DeepEqual(upstream.points, expected) {
+		t.Errorf("Expected the spooled point with its field types, got %v", upstream.points)
+	}
+	if _, err := os.Stat(spoolFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the spool file to be removed, got %v", err)
+	}
+}
+
+// failAfterStore writes successes points and then fails.
+type failAfterStore struct {
+	*flakyStore
+	successes int
+}
+
+func (f *failAfterStore) WritePointAt(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}, pointTime time.Time) error {
+	if len(f.points) >= f.successes {
+		return errConnectionRefused
+	}
+	return f.flakyStore.WritePointAt(ctx, measurement, tags, fields, pointTime)
+}
+
+func TestReplaySpoolSetsAsideRejectedPoints(t *testing.T) {
+	spoolFile := filepath.Join(t.TempDir(), "spool.lp")
+	os.WriteFile(spoolFile, []byte("junit,buildId=1 total_tests=5i 1700000000000000000\n"+
+		"junit,buildId=2 total_tests=6i 1700000060000000000\n"), 0644)
+	rejected := &influxhttp.Error{StatusCode: 400, Code: "invalid", Message: "field type conflict"}
+	upstream := &flakyStore{failures: 1, err: rejected}
+	store, _ := newTestResilientStore(upstream, StoreOptions{Timeout: time.Second, Attempts: 3, SpoolFile: spoolFile})
+
+	if err := store.ReplaySpool(context.Background()); err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if len(upstream.points) != 1 || upstream.points[0]["buildId"] != "2" || !upstream.points[0]["time"].(time.Time).Equal(time.Unix(0, 1700000060000000000)) {
+		t.Errorf("Expected the second point at its spooled time, got %v", upstream.points)
+	}
+	if lines, _ := readSpoolLines(spoolFile + RejectedSpoolSuffix); len(lines) != 1 || !strings.Contains(lines[0], "buildId=1") {
+		t.Errorf("Expected the rejected point to be set aside, got %v", lines)
+	}
+	if _, err := os.Stat(spoolFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the spool file to be removed, got %v", err)
+	}
+	if store.unreachable != nil {
+		t.Errorf("Expected a rejected point not to make the store unreachable")
+	}
+}
+
+func TestGetStoreOptions(t *testing.T) {
+	options, err := GetStoreOptions(Args{})
+	if err != nil || options.Timeout != DefaultStoreTimeout || options.Attempts != DefaultStoreAttempts {
+		t.Errorf("Expected the default store options, got %+v (%v)", options, err)
+	}
+	options, err = GetStoreOptions(Args{StoreTimeout: "5s", StoreAttempts: 1, StoreRetryBackoff: "200ms"})
+	if err != nil || options.Timeout != 5*time.Second || options.Attempts != 1 || options.RetryBackoff != 200*time.Millisecond {
+		t.Errorf("Unexpected store options %+v (%v)", options, err)
+	}
+	for _, args := range []Args{{StoreTimeout: "soon"}, {StoreAttempts: -1}, {StoreRetryBackoff: "-1s"}} {
+		if _, err := GetStoreOptions(args); err == nil {
+			t.Errorf("Expected error for %+v", args)
+		}
+	}
+}
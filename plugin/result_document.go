@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// ResultDocumentVersion is bumped whenever a field of ResultDocument is
+	// renamed or removed. Adding fields keeps the version.
+	ResultDocumentVersion        = 1
+	DefaultResultsDataFile       = "test_results_data.json"
+	TestResultsDataFileOutputVar = "TEST_RESULTS_DATA_FILE"
+)
+
+// ResultDocument is the JSON document written for every run, so downstream
+// steps can read the results without parsing the logs.
+type ResultDocument struct {
+	Version     int                    `json:"version"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Pipeline    ResultDocumentPipeline `json:"pipeline"`
+	Tool        string                 `json:"tool"`
+	Group       string                 `json:"group"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Files       []FileResult           `json:"files"`
+	Modules     []ModuleResult         `json:"modules,omitempty"`
+	Failures    []TestFailure          `json:"failures"`
+	Tests       []TestExecution        `json:"tests,omitempty"`
+	Suites      []TestSuiteResult      `json:"suites,omitempty"`
+	Skipped     []SkippedFile          `json:"skipped_files"`
+	Packages    []Package              `json:"packages,omitempty"`
+	Comparisons []BaselineComparison   `json:"comparisons"`
+	Trend       *TrendReport           `json:"trend,omitempty"`
+	Gates       []GateResult           `json:"gates"`
+	GatesPassed bool                   `json:"gates_passed"`
+}
+
+type ResultDocumentPipeline struct {
+	PipelineId  string `json:"pipeline_id"`
+	BuildId     string `json:"build_id"`
+	BuildNumber int    `json:"build_number"`
+	BuildLink   string `json:"build_link,omitempty"`
+	Event       string `json:"event,omitempty"`
+	Repo        string `json:"repo,omitempty"`
+	Branch      string `json:"branch,omitempty"`
+	Commit      string `json:"commit,omitempty"`
+	PullRequest int    `json:"pull_request,omitempty"`
+	Stage       string `json:"stage,omitempty"`
+	Step        string `json:"step,omitempty"`
+}
+
+// NewResultDocument builds the document of a run. Only the failed and flaky
+// tests are listed unless allTests is set.
+func NewResultDocument(pipeline Pipeline, report BuildReport, allTests bool) ResultDocument {
+	tests := report.Result.Tests
+	if !allTests {
+		tests = failedAndFlakyTests(tests)
+	}
+
+	document := ResultDocument{
+		Version:     ResultDocumentVersion,
+		GeneratedAt: time.Now().UTC(),
+		Pipeline: ResultDocumentPipeline{
+			PipelineId:  report.PipelineId,
+			BuildId:     report.BuildId,
+			BuildNumber: pipeline.Build.Number,
+			BuildLink:   pipeline.Build.Link,
+			Event:       pipeline.Build.Event,
+			Repo:        pipeline.Repo.Slug,
+			Branch:      pipelineTagValues["branch"](pipeline),
+			Commit:      pipeline.Commit.Rev,
+			PullRequest: pipeline.PullRequest.Number,
+			Stage:       pipeline.Stage.Name,
+			Step:        pipeline.Step.Name,
+		},
+		Tool:        report.Tool,
+		Group:       report.Group,
+		Tags:        report.Result.Tags,
+		Fields:      report.Result.Fields,
+		Files:       report.Result.Files,
+		Modules:     report.Result.Modules,
+		Failures:    report.Result.Failures,
+		Tests:       tests,
+		Suites:      report.Result.Suites,
+		Skipped:     report.Result.SkippedFiles,
+		Packages:    report.Result.Packages,
+		Comparisons: report.Comparisons,
+		Trend:       report.Trend,
+		Gates:       report.Gates,
+		GatesPassed: GatesPassed(report.Gates),
+	}
+
+	// Empty lists are written as [] rather than null so consumers can
+	// iterate without checks.
+	if document.Files == nil {
+		document.Files = []FileResult{}
+	}
+	if document.Failures == nil {
+		document.Failures = []TestFailure{}
+	}
+	if document.Skipped == nil {
+		document.Skipped = []SkippedFile{}
+	}
+	if document.Comparisons == nil {
+		document.Comparisons = []BaselineComparison{}
+	}
+	if document.Gates == nil {
+		document.Gates = []GateResult{}
+	}
+	return document
+}
+
+// failedAndFlakyTests keeps the failed executions, and every execution of a
+// test that failed in another one, so reruns show up next to the failure.
+func failedAndFlakyTests(tests []TestExecution) []TestExecution {
+	failed := map[[2]string]bool{}
+	for _, test := range tests {
+		if isFailedStatus(test.Status) {
+			failed[[2]string{test.ClassName, test.Name}] = true
+		}
+	}
+
+	var kept []TestExecution
+	for _, test := range tests {
+		if test.Flaky || failed[[2]string{test.ClassName, test.Name}] {
+			kept = append(kept, test)
+		}
+	}
+	return kept
+}
+
+func WriteResultDocument(path string, document ResultDocument) error {
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteStrToFile(path, string(data))
+}
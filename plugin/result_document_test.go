@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAggregateReportsPerFileResults(t *testing.T) {
+	reportsDir := t.TempDir()
+	for _, name := range []string{"a/TestResult.xml", "b/TestResult.xml"} {
+		path := filepath.Join(reportsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error creating report dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(NunitTestXml), 0644); err != nil {
+			t.Fatalf("Error writing report: %v", err)
+		}
+	}
+	t.Setenv(PipeLineIdEnvVar, mockPipelineId)
+	t.Setenv(BuildNumberEnvVar, mockBuildNumber)
+
+	_, result, err := Aggregate[TestRunSummary](reportsDir, "**/TestResult.xml", ParseOptions{Mode: LenientParseMode},
+		CalculateNunitAggregate, GetNunitDataMaps, func(map[string]string, map[string]interface{}) error { return nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Files) != 2 || result.Files[0].Path != "a/TestResult.xml" || result.Files[1].Path != "b/TestResult.xml" {
+		t.Fatalf("Unexpected per file results: %+v", result.Files)
+	}
+	total, _ := toFloat64(result.Fields["total_cases"])
+	perFile, _ := toFloat64(result.Files[0].Fields["total_cases"])
+	if total != 2*perFile {
+		t.Errorf("Expected total %v to be the sum of the files, got file total %v", total, perFile)
+	}
+}
+
+func TestWriteResultDocument(t *testing.T) {
+	var pipeline Pipeline
+	pipeline.Build.Number = 201
+	pipeline.Repo.Slug = "octocat/hello-world"
+	pipeline.Commit.Branch = "main"
+
+	report := BuildReport{
+		Tool:       JunitTool,
+		Group:      "suite_01",
+		PipelineId: mockPipelineId,
+		BuildId:    mockBuildNumber,
+		Result: AggregateResult{
+			Tool:   JunitTool,
+			Tags:   map[string]string{"pipelineId": mockPipelineId, "buildId": mockBuildNumber},
+			Fields: map[string]interface{}{"total_tests": 3},
+			Files:  []FileResult{{Path: "TEST-a.xml", Fields: map[string]interface{}{"total_tests": 3}}},
+		},
+		Gates: []GateResult{{Gate: "failed_tests<=0", Found: true, Passed: true}},
+	}
+
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := WriteResultDocument(path, NewResultDocument(pipeline, report, false)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading document: %v", err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		t.Fatalf("Error decoding document: %v", err)
+	}
+	if document["version"] != float64(ResultDocumentVersion) || document["tool"] != JunitTool || document["gates_passed"] != true {
+		t.Errorf("Unexpected document header: %v", document)
+	}
+	pipelineInfo := document["pipeline"].(map[string]interface{})
+	if pipelineInfo["build_number"] != float64(201) || pipelineInfo["branch"] != "main" || pipelineInfo["repo"] != "octocat/hello-world" {
+		t.Errorf("Unexpected pipeline metadata: %v", pipelineInfo)
+	}
+	if failures, ok := document["failures"].([]interface{}); !ok || len(failures) != 0 {
+		t.Errorf("Expected an empty failures list, got %v", document["failures"])
+	}
+	if files := document["files"].([]interface{}); len(files) != 1 {
+		t.Errorf("Expected one file result, got %v", files)
+	}
+}
+
+func TestResultDocumentListsFailedAndFlakyTests(t *testing.T) {
+	tests := []TestExecution{
+		{ClassName: "LoginTest", Name: "testLogin", Status: "failed"},
+		{ClassName: "LoginTest", Name: "testLogout", Status: "passed"},
+		{ClassName: "LoginTest", Name: "testLogin", Status: "passed"},
+		{ClassName: "CartTest", Name: "testAdd", Status: "passed", Flaky: true},
+		{ClassName: "CartTest", Name: "testRemove", Status: "error"},
+	}
+	report := BuildReport{Tool: JunitTool, Result: AggregateResult{Tests: tests}}
+
+	document := NewResultDocument(Pipeline{}, report, false)
+	expected := []string{"LoginTest.testLogin/failed", "LoginTest.testLogin/passed", "CartTest.testAdd/passed", "CartTest.testRemove/error"}
+	var got []string
+	for _, test := range document.Tests {
+		got = append(got, test.ClassName+"."+test.Name+"/"+test.Status)
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected tests %v, got %v", expected, got)
+	}
+
+	if document := NewResultDocument(Pipeline{}, report, true); len(document.Tests) != len(tests) {
+		t.Errorf("Expected every test with results_data_all_tests, got %d", len(document.Tests))
+	}
+}
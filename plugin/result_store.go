@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ResultStore persists aggregated build results and reads them back for
+// comparisons. Each aggregator writes one point per build, keyed by the
+// tool (measurement) and the pipelineId, buildId and group tags.
+type ResultStore interface {
+	WritePoint(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}) error
+	FetchBuild(ctx context.Context, query BuildQuery, buildId string) (map[string]float64, error)
+	ListBuilds(ctx context.Context, query BuildQuery) ([]BuildRecord, error)
+	Close()
+}
+
+// TimedPointWriter is implemented by stores that can write a point with its
+// own time instead of the current one. ReplaySpool uses it so spooled points
+// keep the time of the build they belong to.
+type TimedPointWriter interface {
+	WritePointAt(ctx context.Context, measurement string, tags map[string]string, fields map[string]interface{}, pointTime time.Time) error
+}
+
+// BuildQuery selects the builds of one pipeline and group for a tool.
+type BuildQuery struct {
+	Measurement string
+	PipelineId  string
+	Group       string
+}
+
+// BuildRecord is a single stored build as returned by ListBuilds.
+type BuildRecord struct {
+	BuildId string
+	Time    time.Time
+	Tags    map[string]string
+	Fields  map[string]float64
+}
+
+const (
+	InfluxDbStoreType  = "influxdb"
+	InfluxDb1StoreType = "influxdb1"
+	FileStoreType      = "file"
+)
+
+// NewResultStore returns the store selected by PLUGIN_STORE_TYPE. When no
+// type is set, InfluxDB v2 is used if its settings are complete, then
+// InfluxDB 1.x if a database is given, and results are not persisted
+// otherwise. In InfluxDB dry-run mode the selected InfluxDB store is only
+// read from and points are written to a line protocol file instead.
+func NewResultStore(args Args) (ResultStore, error) {
+	credentials := DbCredentials{
+		InfluxDBURL:     args.DbUrl,
+		InfluxDBToken:   args.DbToken,
+		Organization:    args.DbOrg,
+		Bucket:          args.DbBucket,
+		Database:        args.DbDatabase,
+		RetentionPolicy: args.DbRetentionPolicy,
+		Username:        args.DbUsername,
+		Password:        args.DbPassword,
+	}
+	influxConfigured := args.DbUrl != "" && args.DbToken != "" && args.DbOrg != "" && args.DbBucket != ""
+	influx1Configured := args.DbUrl != "" && args.DbDatabase != ""
+
+	var store ResultStore
+	switch args.StoreType {
+	case "":
+		if influxConfigured {
+			store = NewInfluxDbStore(credentials)
+		} else if influx1Configured {
+			store = NewInfluxDb1Store(credentials)
+		}
+	case InfluxDbStoreType:
+		if !influxConfigured {
+			return nil, fmt.Errorf("store type %s requires influxdb_url, influxdb_token, influxdb_org and influxdb_bucket", InfluxDbStoreType)
+		}
+		store = NewInfluxDbStore(credentials)
+	case InfluxDb1StoreType:
+		if !influx1Configured {
+			return nil, fmt.Errorf("store type %s requires influxdb_url and influxdb_database", InfluxDb1StoreType)
+		}
+		store = NewInfluxDb1Store(credentials)
+	case FileStoreType:
+		fileStore := NewFileResultStore(args.StoreFile)
+		logrus.Println("Using file result store ", fileStore.Path)
+		return fileStore, nil
+	default:
+		return nil, fmt.Errorf("store type %s not supported", args.StoreType)
+	}
+
+	if args.DbDryRun {
+		lineProtocolStore := NewLineProtocolFileStore(args.LineProtocolFile, store)
+		logrus.Println("InfluxDB dry run, writing line protocol to ", lineProtocolStore.Path)
+		return lineProtocolStore, nil
+	}
+	return store, nil
+}
+
+func PersistResults(ctx context.Context, store ResultStore, measurementName, groupName string,
+	tagsMap map[string]string, fieldsMap map[string]interface{}) error {
+
+	if store == nil {
+		logrus.Println("No result store configured, skipping persisting results")
+		return nil
+	}
+
+	tagsMap["group"] = groupName
+	err := store.WritePoint(ctx, measurementName, tagsMap, fieldsMap)
+	if err != nil {
+		logrus.Println("Error writing point: ", err)
+		return err
+	}
+	logrus.Println("Data persisted successfully to result store.")
+	return nil
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
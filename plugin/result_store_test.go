@@ -0,0 +1,257 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func mockStoreWithBuilds(t *testing.T, buildFields map[string]map[string]interface{}) *MemoryResultStore {
+	store := NewMemoryResultStore()
+	for buildId, fields := range buildFields {
+		tags := map[string]string{"pipelineId": mockPipelineId, "buildId": buildId}
+		if err := PersistResults(context.Background(), store, JunitTool, "suite_01", tags, fields); err != nil {
+			t.Fatalf("Error persisting build %s: %v", buildId, err)
+		}
+	}
+	return store
+}
+
+func TestResolveBaselinesFromStore(t *testing.T) {
+	store := mockStoreWithBuilds(t, map[string]map[string]interface{}{
+		"99":  {"total_tests": 10},
+		"105": {"total_tests": 12},
+		"102": {"total_tests": 11},
+		"abc": {"total_tests": 1},
+	})
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	tests := []struct {
+		name          string
+		currentBuild  string
+		compareBuild  string
+		expectedBuild int
+		expectErr     bool
+	}{
+		{"Latest earlier build", "105", "", 102, false},
+		{"Gap in build numbers", "101", "", 99, false},
+		{"No previous build", "99", "", 0, true},
+		{"Explicit compare build", "105", "42", 42, false},
+		{"Invalid currentBuildId", "xyz", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevBuild := 0
+			baselines, err := ResolveBaselines(context.Background(), store, query, tt.currentBuild, Args{CompareBuildId: tt.compareBuild})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if err == nil {
+				prevBuild = baselines[0].BuildId
+			}
+			if prevBuild != tt.expectedBuild {
+				t.Errorf("Expected previous build ID: %d, got: %d", tt.expectedBuild, prevBuild)
+			}
+		})
+	}
+}
+
+func TestCompareWithBaselinesFromStore(t *testing.T) {
+	store := mockStoreWithBuilds(t, map[string]map[string]interface{}{
+		"1": {"total_tests": 10, "failed_tests": 2},
+		"2": {"total_tests": 12, "failed_tests": 1},
+	})
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	comparisons, err := CompareWithBaselines(context.Background(), store, query, "2",
+		[]Baseline{{Selector: PreviousBuildStrategy, BuildId: 1}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resultStr, err := ComparisonsToCsv(comparisons)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedCsvRows := []string{
+		"total_tests,12.00,10.00,2.00,20.00%",
+		"failed_tests,1.00,2.00,-1.00,-50.00%",
+	}
+	for _, expectedRow := range expectedCsvRows {
+		if !strings.Contains(resultStr, expectedRow) {
+			t.Errorf("Expected row not found in result: %q", expectedRow)
+		}
+	}
+}
+
+func TestMemoryResultStoreFiltersByQuery(t *testing.T) {
+	store := NewMemoryResultStore()
+	ctx := context.Background()
+	_ = store.WritePoint(ctx, JunitTool, map[string]string{"pipelineId": "p1", "buildId": "1", "group": "g1"},
+		map[string]interface{}{"total_tests": 1})
+	_ = store.WritePoint(ctx, JunitTool, map[string]string{"pipelineId": "p1", "buildId": "2", "group": "g2"},
+		map[string]interface{}{"total_tests": 2})
+	_ = store.WritePoint(ctx, JacocoTool, map[string]string{"pipelineId": "p1", "buildId": "3", "group": "g1"},
+		map[string]interface{}{"line_covered_sum": 3.0})
+
+	records, err := store.ListBuilds(ctx, BuildQuery{Measurement: JunitTool, PipelineId: "p1", Group: "g1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || records[0].BuildId != "1" {
+		t.Errorf("Expected only build 1, got %+v", records)
+	}
+}
+
+func TestResolveBaselinesTargetBranch(t *testing.T) {
+	store := NewMemoryResultStore()
+	builds := []map[string]string{
+		{"buildId": "10", "branch": "main", "event": "push", "buildStatus": "success"},
+		{"buildId": "11", "branch": "feature/a", "event": "pull_request"},
+		{"buildId": "12", "branch": "main", "event": "push", "buildStatus": "failure"},
+		{"buildId": "13", "branch": "main", "event": "pull_request"},
+		{"buildId": "14", "branch": "release", "event": "push", "buildStatus": "success"},
+		{"buildId": "15", "branch": "feature/b", "event": "pull_request"},
+	}
+	for _, tags := range builds {
+		tags["pipelineId"] = mockPipelineId
+		if err := PersistResults(context.Background(), store, JunitTool, "suite_01", tags, map[string]interface{}{"total_tests": 1}); err != nil {
+			t.Fatalf("Error persisting build: %v", err)
+		}
+	}
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	args := Args{CompareStrategy: TargetBranchStrategy}
+	args.Commit.Target = "main"
+	baselines, err := ResolveBaselines(context.Background(), store, query, "15", args)
+	if err != nil || baselines[0].BuildId != 10 {
+		t.Errorf("Expected baseline 10 on main, got %+v (%v)", baselines, err)
+	}
+
+	args.BaselineBranch = "release"
+	baselines, err = ResolveBaselines(context.Background(), store, query, "15", args)
+	if err != nil || baselines[0].BuildId != 14 {
+		t.Errorf("Expected baseline 14 on release, got %+v (%v)", baselines, err)
+	}
+
+	args.BaselineBranch = "develop"
+	if _, err = ResolveBaselines(context.Background(), store, query, "15", args); err == nil {
+		t.Errorf("Expected error when the baseline branch has no builds")
+	}
+
+	if _, err = ResolveBaselines(context.Background(), store, query, "15", Args{CompareStrategy: TargetBranchStrategy}); err == nil {
+		t.Errorf("Expected error without a target branch")
+	}
+}
+
+func TestResolveBaselineSelectors(t *testing.T) {
+	records := []BuildRecord{
+		{BuildId: "10", Tags: map[string]string{"tag": "v1.0.0", "semver": "1.0.0", "commit": "aaa111"}},
+		{BuildId: "11", Tags: map[string]string{"commit": "bbb222", "baseline": "golden"}},
+		{BuildId: "12", Tags: map[string]string{"tag": "v1.1.0", "semver": "1.1.0", "commit": "ccc333"}},
+		{BuildId: "13", Tags: map[string]string{"tag": "v1.2.0-rc.1", "semver": "1.2.0-rc.1"}},
+		{BuildId: "14", Tags: map[string]string{"commit": "ddd444"}},
+	}
+
+	tests := []struct {
+		selector      string
+		expectedBuild int
+		expectErr     bool
+	}{
+		{"previous", 14, false},
+		{"build:7", 7, false},
+		{"tag", 13, false},
+		{"tag:v1.0.0", 10, false},
+		{"semver:v1.1.0", 12, false},
+		{"release", 12, false},
+		{"commit:BBB", 11, false},
+		{"pinned", 11, false},
+		{"pinned:golden", 11, false},
+		{"pinned:silver", 0, true},
+		{"commit:fff", 0, true},
+		{"semver", 0, true},
+		{"unknown", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			buildId, err := ResolveBaseline(records, 15, tt.selector, Args{})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if buildId != tt.expectedBuild {
+				t.Errorf("Expected build ID: %d, got: %d", tt.expectedBuild, buildId)
+			}
+		})
+	}
+}
+
+func TestResolveBaselineNotFoundMessages(t *testing.T) {
+	records := []BuildRecord{{BuildId: "10", Tags: map[string]string{}}}
+	tests := map[string]string{
+		"tag":           "no tag build found before build 15",
+		"tag:v2.0.0":    "no build found for git tag v2.0.0",
+		"pinned":        "no pinned baseline found before build 15",
+		"pinned:golden": "no pinned baseline golden found",
+	}
+	for selector, expected := range tests {
+		if _, err := ResolveBaseline(records, 15, selector, Args{}); err == nil || err.Error() != expected {
+			t.Errorf("Expected %q for %s, got %v", expected, selector, err)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.0", "v1.2.0", 0},
+		{"1.10.0", "1.9.3", 1},
+		{"1.2.0-rc.1", "1.2.0", -1},
+		{"1.2.0-rc.2", "1.2.0-rc.10", -1},
+		{"1.2.0+build.5", "1.2.0", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareSemver(tt.a, tt.b); got != tt.expected {
+			t.Errorf("CompareSemver(%s, %s) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestCompareWithMultipleBaselines(t *testing.T) {
+	store := mockStoreWithBuilds(t, map[string]map[string]interface{}{
+		"1": {"total_tests": 8},
+		"2": {"total_tests": 10},
+		"3": {"total_tests": 12},
+	})
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	baselines, err := ResolveBaselines(context.Background(), store, query, "3", Args{CompareBaselines: "previous, build:1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(baselines) != 2 || baselines[0].BuildId != 2 || baselines[1].BuildId != 1 {
+		t.Fatalf("Unexpected baselines: %+v", baselines)
+	}
+
+	comparisons, err := CompareWithBaselines(context.Background(), store, query, "3", baselines)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resultStr, err := ComparisonsToCsv(comparisons)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedCsvRows := []string{
+		"Baseline,Field Name,Current,Previous,Difference,Percentage Difference",
+		"previous,total_tests,12.00,10.00,2.00,20.00%",
+		"build:1,total_tests,12.00,8.00,4.00,50.00%",
+	}
+	for _, expectedRow := range expectedCsvRows {
+		if !strings.Contains(resultStr, expectedRow) {
+			t.Errorf("Expected row not found in result: %q", expectedRow)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package plugin
+
+import "fmt"
+
+// CountFailedTests returns the failed and errored tests of the build fields,
+// and false for tools that do not report tests.
+func CountFailedTests(fields map[string]interface{}) (int, bool) {
+	for _, names := range testCountFields {
+		if _, exists := fields[names.Total]; !exists {
+			continue
+		}
+		failed, _ := toFloat64(fields[names.Failed])
+		errored, _ := toFloat64(fields[names.Errors])
+		return int(failed + errored), true
+	}
+	return 0, false
+}
+
+// CheckTestFailures returns ErrTestsFailed when fail_on_test_failure is set
+// and the aggregated reports contain failed tests.
+func CheckTestFailures(args Args, result AggregateResult) error {
+	if !args.FailOnTestFailure {
+		return nil
+	}
+	failed, isTestTool := CountFailedTests(result.Fields)
+	if !isTestTool || failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d failed tests in the %s reports", ErrTestsFailed, failed, result.Tool)
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTestFailures(t *testing.T) {
+	junitResult := AggregateResult{Tool: JunitTool, Fields: map[string]interface{}{
+		"total_tests": 5, "failed_tests": 1, "errors_count": 1,
+	}}
+	nunitResult := AggregateResult{Tool: NunitTool, Fields: map[string]interface{}{
+		"total_cases": 5, "total_failed": 0,
+	}}
+	jacocoResult := AggregateResult{Tool: JacocoTool, Fields: map[string]interface{}{
+		"line_covered_sum": 10, "line_missed_sum": 2,
+	}}
+
+	if err := CheckTestFailures(Args{}, junitResult); err != nil {
+		t.Errorf("Expected failed tests to be accepted by default, got %v", err)
+	}
+	err := CheckTestFailures(Args{FailOnTestFailure: true}, junitResult)
+	if !errors.Is(err, ErrTestsFailed) || err.Error() != "tests failed: 2 failed tests in the junit reports" {
+		t.Errorf("Expected the tests failed error, got %v", err)
+	}
+	if err := CheckTestFailures(Args{FailOnTestFailure: true}, nunitResult); err != nil {
+		t.Errorf("Expected no error without failed tests, got %v", err)
+	}
+	if err := CheckTestFailures(Args{FailOnTestFailure: true}, jacocoResult); err != nil {
+		t.Errorf("Expected no error for coverage results, got %v", err)
+	}
+}
+
+func TestJunitAggregateDoesNotFailOnFailedTests(t *testing.T) {
+	reportsDir := t.TempDir()
+	os.WriteFile(filepath.Join(reportsDir, "TEST-run1.xml"), []byte(firstRunJunitXml), 0644)
+	t.Setenv("HARNESS_PIPELINE_ID", "pipeline")
+	t.Setenv("HARNESS_BUILD_ID", "1")
+	t.Setenv("DRONE_OUTPUT", filepath.Join(t.TempDir(), "output.env"))
+
+	result, err := GetNewJunitAggregator(reportsDir, "", "*.xml", ParseOptions{Mode: LenientParseMode}).Aggregate()
+	if err != nil {
+		t.Fatalf("Expected failed tests to be aggregated without error, got %v", err)
+	}
+	if failed, _ := CountFailedTests(result.Fields); failed != 2 {
+		t.Errorf("Expected 2 failed tests, got %d", failed)
+	}
+}
@@ -10,10 +10,10 @@ import (
 )
 
 type TestNgAggregator struct {
-	ReportsDir  string
-	ReportsName string
-	Includes    string
-	DbCredentials
+	ReportsDir   string
+	ReportsName  string
+	Includes     string
+	ParseOptions ParseOptions
 }
 
 type TestNGResults struct {
@@ -29,8 +29,12 @@ type TestNGReport struct {
 	XMLName           xml.Name `xml:"testng-results"`
 	Suites            []Suite  `xml:"suite"`
 	AggregatedResults Results
+	Failures          []TestFailure `xml:"-"`
 }
 
+// Suite is a TestNG suite. Decoding a TestNGReport aggregates the classes
+// as they are read and leaves Classes empty; decode a <suite> element into a
+// Suite to keep them.
 type Suite struct {
 	Name     string  `xml:"name,attr"`
 	Duration string  `xml:"duration-ms,attr"`
@@ -63,6 +67,85 @@ type Test struct {
 	Exception   string `xml:"exception>short-stacktrace"`
 }
 
+// UnmarshalXML reads the report one token at a time. Each class is decoded
+// on its own and added to AggregatedResults and Failures before the next one
+// is read, so only one class is held in memory at a time.
+func (r *TestNGReport) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Local != "testng-results" {
+		return fmt.Errorf("expected element type <testng-results> but have <%s>", start.Name.Local)
+	}
+	r.XMLName = start.Name
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "suite":
+				suite := Suite{}
+				for _, attr := range element.Attr {
+					switch attr.Name.Local {
+					case "name":
+						suite.Name = attr.Value
+					case "duration-ms":
+						suite.Duration = attr.Value
+					}
+				}
+				r.Suites = append(r.Suites, suite)
+			case "groups":
+				var groups struct {
+					Groups []Group `xml:"group"`
+				}
+				if err := d.DecodeElement(&groups, &element); err != nil {
+					return err
+				}
+				if len(r.Suites) > 0 {
+					suite := &r.Suites[len(r.Suites)-1]
+					suite.Groups = append(suite.Groups, groups.Groups...)
+				}
+			case "test":
+				// the classes of the test are read as the next tokens
+			case "class":
+				var class Class
+				if err := d.DecodeElement(&class, &element); err != nil {
+					return err
+				}
+				r.addClassResults(class)
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == start.Name.Local {
+				return nil
+			}
+		}
+	}
+}
+
+func (r *TestNGReport) addClassResults(class Class) {
+	classResults, _, _ := aggregateClassResults(class)
+	r.AggregatedResults.Total += classResults.Total
+	r.AggregatedResults.Failures += classResults.Failures
+	r.AggregatedResults.Skipped += classResults.Skipped
+	r.AggregatedResults.DurationMS += classResults.DurationMS
+
+	for _, test := range class.Tests {
+		if test.Status == "FAIL" {
+			r.Failures = append(r.Failures, TestFailure{
+				ClassName: class.Name,
+				Name:      test.Name,
+				Status:    "failed",
+				Message:   strings.TrimSpace(test.Exception),
+			})
+		}
+	}
+}
+
 type Results struct {
 	Total      int
 	Failures   int
@@ -71,61 +154,43 @@ type Results struct {
 }
 
 func GetNewTestNgAggregator(
-	reportsDir, reportsName, includes, dbUrl, dbToken, dbOrg, dbBucket string) *TestNgAggregator {
+	reportsDir, reportsName, includes string, options ParseOptions) *TestNgAggregator {
 	return &TestNgAggregator{
-		ReportsDir:  reportsDir,
-		ReportsName: reportsName,
-		Includes:    includes,
-		DbCredentials: DbCredentials{
-			InfluxDBURL:   dbUrl,
-			InfluxDBToken: dbToken,
-			Organization:  dbOrg,
-			Bucket:        dbBucket,
-		},
+		ReportsDir:   reportsDir,
+		ReportsName:  reportsName,
+		Includes:     includes,
+		ParseOptions: options,
 	}
 }
 
-func (t *TestNgAggregator) Aggregate(groupName string) error {
+func (t *TestNgAggregator) Aggregate() (AggregateResult, error) {
 	logrus.Println("TestNgAggregator Aggregator Aggregate")
 
-	tagsMap, fieldsMap, err := Aggregate[TestNGReport](t.ReportsDir, t.Includes,
-		t.DbCredentials.InfluxDBURL, t.DbCredentials.InfluxDBToken,
-		t.DbCredentials.Organization, t.DbCredentials.Bucket, TestNgTool, groupName,
+	aggregate, result, err := Aggregate[TestNGReport](t.ReportsDir, t.Includes, t.ParseOptions,
 		CalculateTestNgAggregate, GetTestNgDataMaps, ShowTestNgStats)
+	result.Tool, result.Failures = TestNgTool, aggregate.Failures
 	if err != nil {
 		logrus.Errorf("Error aggregating TestNG results: %v", err)
-		return err
+		return result, err
 	}
 
-	err = ExportTestNgOutputVars(tagsMap, fieldsMap)
+	err = ExportTestNgOutputVars(result.Tags, result.Fields)
 	if err != nil {
 		logrus.Println("Error exporting TestNG output variables", err)
-		return err
+		return result, err
 	}
-	return nil
+	return result, nil
 }
 
 func CalculateTestNgAggregate(testNgAggregatorList []TestNGReport) TestNGReport {
 	aggregatorData := TestNGReport{}
-	var totalTests, totalFailures, totalSkipped int
-	var totalDuration float64
 
 	for _, report := range testNgAggregatorList {
-		for _, suite := range report.Suites {
-			suiteResults, _, _ := aggregateSuiteResults(suite)
-
-			totalTests += suiteResults.Total
-			totalFailures += suiteResults.Failures
-			totalSkipped += suiteResults.Skipped
-			totalDuration += suiteResults.DurationMS
-		}
-	}
-
-	aggregatorData.AggregatedResults = Results{
-		Total:      totalTests,
-		Failures:   totalFailures,
-		Skipped:    totalSkipped,
-		DurationMS: totalDuration,
+		aggregatorData.AggregatedResults.Total += report.AggregatedResults.Total
+		aggregatorData.AggregatedResults.Failures += report.AggregatedResults.Failures
+		aggregatorData.AggregatedResults.Skipped += report.AggregatedResults.Skipped
+		aggregatorData.AggregatedResults.DurationMS += report.AggregatedResults.DurationMS
+		aggregatorData.Failures = append(aggregatorData.Failures, report.Failures...)
 	}
 
 	return aggregatorData
@@ -145,6 +210,7 @@ func GetTestNgDataMaps(pipelineId, buildNumber string,
 		"total_skipped": aggregateData.AggregatedResults.Skipped,
 		"duration_ms":   aggregateData.AggregatedResults.DurationMS,
 	}
+	AddDerivedFields(fields)
 
 	return tags, fields
 }
@@ -166,25 +232,6 @@ func ExportTestNgOutputVars(tagsMap map[string]string, fieldsMap map[string]inte
 	return nil
 }
 
-func aggregateSuiteResults(suite Suite) (Results, []string, []string) {
-	results := Results{}
-	var failedTests []string
-	var skippedTests []string
-
-	for _, class := range suite.Classes {
-		classResults, failed, skipped := aggregateClassResults(class)
-		results.Total += classResults.Total
-		results.Failures += classResults.Failures
-		results.Skipped += classResults.Skipped
-		results.DurationMS += classResults.DurationMS
-
-		failedTests = append(failedTests, failed...)
-		skippedTests = append(skippedTests, skipped...)
-	}
-
-	return results, failedTests, skippedTests
-}
-
 func aggregateClassResults(class Class) (Results, []string, []string) {
 	results := Results{}
 	var failedTests []string
@@ -220,6 +267,7 @@ func ShowTestNgStats(tagsMap map[string]string, fieldsMap map[string]interface{}
 		"total_failed":  "❌ Total Failed",
 		"total_skipped": "🟦 Total Skipped",
 		"duration_ms":   "⏱️ Total Duration (ms) ",
+		PassRateField:   "📈 Pass Rate (%)",
 	}
 
 	col1Width := len("Test Category")
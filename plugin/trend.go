@@ -0,0 +1,353 @@
+package plugin
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	BuildResultsTrendCsv              = "build_results_trend.csv"
+	BuildResultsTrendJson             = "build_results_trend.json"
+	TestResultsTrendFileOutputVar     = "TEST_RESULTS_TREND_FILE"
+	TestResultsTrendJsonFileOutputVar = "TEST_RESULTS_TREND_JSON_FILE"
+)
+
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// TrendBuild is the merged set of fields stored for one build.
+type TrendBuild struct {
+	BuildId string             `json:"build_id"`
+	Time    time.Time          `json:"time"`
+	Fields  map[string]float64 `json:"fields"`
+}
+
+// FieldTrend summarises one field across the builds of a trend report that
+// have it. Values are those builds' values, oldest first, and Slope is the
+// least squares change of the field per build.
+type FieldTrend struct {
+	Field  string    `json:"field"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Mean   float64   `json:"mean"`
+	Slope  float64   `json:"slope"`
+	Values []float64 `json:"values"`
+}
+
+type TrendReport struct {
+	Tool       string       `json:"tool"`
+	PipelineId string       `json:"pipeline_id"`
+	Group      string       `json:"group"`
+	Builds     []TrendBuild `json:"builds"`
+	Fields     []FieldTrend `json:"fields"`
+}
+
+// ReportBuildTrend renders the trend of the last PLUGIN_TREND_BUILDS builds,
+// or of the builds stored within PLUGIN_TREND_WINDOW, and exports it as CSV
+// and JSON to PLUGIN_DIFF_OUTPUT_DIR.
+func ReportBuildTrend(ctx context.Context, args Args, store ResultStore) (*TrendReport, error) {
+	if store == nil {
+		return nil, errors.New("trend reports require a result store, configure InfluxDB or set store_type to file")
+	}
+
+	window, err := ParseTrendWindow(args.TrendWindow)
+	if err != nil {
+		logrus.Println("Invalid trend window ", err)
+		return nil, err
+	}
+
+	pipelineId, buildNumber, err := GetPipelineInfo()
+	if err != nil {
+		fmt.Println("ReportBuildTrend Error getting pipeline info: ", err)
+		return nil, err
+	}
+
+	query := BuildQuery{Measurement: args.Tool, PipelineId: pipelineId, Group: args.GroupName}
+	report, err := GetBuildTrend(ctx, store, query, buildNumber, args.TrendBuilds, window, time.Now())
+	if err != nil {
+		logrus.Println("Unable to get build trend ", err)
+		return nil, err
+	}
+
+	fmt.Println("")
+	fmt.Printf("Trend over the last %d builds:\n", len(report.Builds))
+	ShowTrendAsTable(report)
+	fmt.Println("")
+
+	if args.DiffOutputDir != "" {
+		if err := os.MkdirAll(args.DiffOutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create trend output directory: %w", err)
+		}
+	}
+
+	csvStr, err := TrendToCsv(report)
+	if err != nil {
+		logrus.Println("Unable to render trend CSV ", err)
+		return nil, err
+	}
+	if err := ExportComparisonResults(filepath.Join(args.DiffOutputDir, BuildResultsTrendCsv), csvStr, TestResultsTrendFileOutputVar); err != nil {
+		return nil, err
+	}
+
+	jsonStr, err := ToJsonStringFromStruct(report)
+	if err != nil {
+		logrus.Println("Unable to render trend JSON ", err)
+		return nil, err
+	}
+	return &report, ExportComparisonResults(filepath.Join(args.DiffOutputDir, BuildResultsTrendJson), jsonStr, TestResultsTrendJsonFileOutputVar)
+}
+
+// ParseTrendWindow parses a Go duration such as "72h", with an added "d"
+// suffix for days such as "30d". An empty window means no time limit.
+func ParseTrendWindow(window string) (time.Duration, error) {
+	window = strings.TrimSpace(window)
+	if window == "" {
+		return 0, nil
+	}
+	if days, found := strings.CutSuffix(window, "d"); found {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid trend window %s", window)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid trend window %s", window)
+	}
+	return duration, nil
+}
+
+// GetBuildTrend collects the builds up to and including currentBuildId,
+// oldest first. Builds older than window are dropped when window is set,
+// and only the last maxBuilds are kept when maxBuilds is set.
+func GetBuildTrend(ctx context.Context, store ResultStore, query BuildQuery, currentBuildId string,
+	maxBuilds int, window time.Duration, now time.Time) (TrendReport, error) {
+
+	report := TrendReport{Tool: query.Measurement, PipelineId: query.PipelineId, Group: query.Group}
+
+	currentBuild, err := strconv.Atoi(currentBuildId)
+	if err != nil {
+		return report, fmt.Errorf("invalid currentBuildId: %s", currentBuildId)
+	}
+
+	records, err := store.ListBuilds(ctx, query)
+	if err != nil {
+		return report, fmt.Errorf("failed to list builds: %w", err)
+	}
+
+	buildsById := map[int]*TrendBuild{}
+	for _, record := range records {
+		buildId, err := strconv.Atoi(record.BuildId)
+		if err != nil || buildId > currentBuild {
+			continue
+		}
+		if window > 0 && !record.Time.IsZero() && record.Time.Before(now.Add(-window)) {
+			continue
+		}
+		build, exists := buildsById[buildId]
+		if !exists {
+			build = &TrendBuild{BuildId: record.BuildId, Fields: map[string]float64{}}
+			buildsById[buildId] = build
+		}
+		if record.Time.After(build.Time) {
+			build.Time = record.Time
+		}
+		for key, value := range record.Fields {
+			build.Fields[key] = value
+		}
+	}
+
+	var buildIds []int
+	for buildId := range buildsById {
+		buildIds = append(buildIds, buildId)
+	}
+	sort.Ints(buildIds)
+	if maxBuilds > 0 && len(buildIds) > maxBuilds {
+		buildIds = buildIds[len(buildIds)-maxBuilds:]
+	}
+	if len(buildIds) == 0 {
+		return report, fmt.Errorf("no builds found for the trend of %s", currentBuildId)
+	}
+
+	for _, buildId := range buildIds {
+		// builds stored before the derived fields existed get them here
+		fillDerivedFields(buildsById[buildId].Fields)
+		report.Builds = append(report.Builds, *buildsById[buildId])
+	}
+	report.Fields = ComputeFieldTrends(report.Builds)
+	return report, nil
+}
+
+// ComputeFieldTrends returns the statistics of every field found in builds.
+// Builds without a field are left out of the statistics of that field.
+func ComputeFieldTrends(builds []TrendBuild) []FieldTrend {
+	allFields := map[string]struct{}{}
+	for _, build := range builds {
+		for field := range build.Fields {
+			allFields[field] = struct{}{}
+		}
+	}
+	var sortedFields []string
+	for field := range allFields {
+		sortedFields = append(sortedFields, field)
+	}
+	sort.Strings(sortedFields)
+
+	var trends []FieldTrend
+	for _, field := range sortedFields {
+		trend := FieldTrend{Field: field, Min: math.Inf(1), Max: math.Inf(-1)}
+		var positions []float64
+		sum := 0.0
+		for i, build := range builds {
+			value, exists := build.Fields[field]
+			if !exists {
+				continue
+			}
+			positions = append(positions, float64(i))
+			trend.Values = append(trend.Values, value)
+			trend.Min = math.Min(trend.Min, value)
+			trend.Max = math.Max(trend.Max, value)
+			sum += value
+		}
+		trend.Mean = sum / float64(len(trend.Values))
+		trend.Slope = computeSlope(positions, trend.Values)
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// computeSlope fits values at the build positions with least squares.
+func computeSlope(positions, values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	meanX, meanY := 0.0, 0.0
+	for i, value := range values {
+		meanX += positions[i]
+		meanY += value
+	}
+	meanX /= n
+	meanY /= n
+
+	numerator, denominator := 0.0, 0.0
+	for i, value := range values {
+		dx := positions[i] - meanX
+		numerator += dx * (value - meanY)
+		denominator += dx * dx
+	}
+	return numerator / denominator
+}
+
+// Sparkline renders values as a row of block characters scaled between the
+// smallest and largest value.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+
+	var sb strings.Builder
+	for _, value := range values {
+		index := 0
+		if maxValue > minValue {
+			index = int(math.Round((value - minValue) / (maxValue - minValue) * float64(len(sparklineBlocks)-1)))
+		}
+		sb.WriteRune(sparklineBlocks[index])
+	}
+	return sb.String()
+}
+
+func ShowTrendAsTable(report TrendReport) {
+	headers := []string{"Result Type", "Min", "Max", "Mean", "Slope", "Trend"}
+	var rows [][]string
+	for _, trend := range report.Fields {
+		rows = append(rows, []string{
+			trend.Field,
+			fmt.Sprintf("%.2f", trend.Min),
+			fmt.Sprintf("%.2f", trend.Max),
+			fmt.Sprintf("%.2f", trend.Mean),
+			fmt.Sprintf("%+.2f", trend.Slope),
+			Sparkline(trend.Values),
+		})
+	}
+
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	totalWidth := 1
+	for _, width := range widths {
+		totalWidth += width + 3
+	}
+	printRow := func(cells []string) {
+		for i, cell := range cells {
+			fmt.Printf("| %s%s ", cell, strings.Repeat(" ", widths[i]-len([]rune(cell))))
+		}
+		fmt.Println("|")
+	}
+
+	fmt.Println(strings.Repeat("-", totalWidth))
+	printRow(headers)
+	fmt.Println(strings.Repeat("-", totalWidth))
+	for _, row := range rows {
+		printRow(row)
+	}
+	fmt.Println(strings.Repeat("-", totalWidth))
+}
+
+// TrendToCsv renders one row per build with a column per field, the layout
+// most charting tools expect. Fields a build does not have are left empty.
+func TrendToCsv(report TrendReport) (string, error) {
+	var csvBuffer strings.Builder
+	writer := csv.NewWriter(&csvBuffer)
+
+	header := []string{"Build ID", "Time"}
+	for _, trend := range report.Fields {
+		header = append(header, trend.Field)
+	}
+	records := [][]string{header}
+
+	for _, build := range report.Builds {
+		buildTime := ""
+		if !build.Time.IsZero() {
+			buildTime = build.Time.UTC().Format(time.RFC3339)
+		}
+		record := []string{build.BuildId, buildTime}
+		for _, trend := range report.Fields {
+			value, exists := build.Fields[trend.Field]
+			if !exists {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		records = append(records, record)
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return "", err
+	}
+	return csvBuffer.String(), nil
+}
@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetBuildTrend(t *testing.T) {
+	store := mockStoreWithBuilds(t, map[string]map[string]interface{}{
+		"1": {"total_tests": 10, "failed_tests": 4},
+		"2": {"total_tests": 12, "failed_tests": 3},
+		"3": {"total_tests": 14, "failed_tests": 2},
+		"4": {"total_tests": 16, "failed_tests": 1},
+		"5": {"total_tests": 99},
+	})
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+
+	report, err := GetBuildTrend(context.Background(), store, query, "4", 3, 0, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Builds) != 3 || report.Builds[0].BuildId != "2" || report.Builds[2].BuildId != "4" {
+		t.Fatalf("Expected builds 2 to 4, got %+v", report.Builds)
+	}
+
+	// the stored builds have no rates, they are derived from the counts
+	expected := map[string]FieldTrend{
+		"total_tests":  {Min: 12, Max: 16, Mean: 14, Slope: 2},
+		"failed_tests": {Min: 1, Max: 3, Mean: 2, Slope: -1},
+		PassRateField:  {Min: 75, Max: 93.75, Mean: 84.82142857142857, Slope: 9.375},
+		"failure_rate": {Min: 6.25, Max: 25, Mean: 15.178571428571429, Slope: -9.375},
+		"skip_rate":    {},
+	}
+	for _, trend := range report.Fields {
+		want := expected[trend.Field]
+		if trend.Min != want.Min || trend.Max != want.Max || math.Abs(trend.Mean-want.Mean) > 1e-9 || math.Abs(trend.Slope-want.Slope) > 1e-9 {
+			t.Errorf("Unexpected trend for %s: %+v", trend.Field, trend)
+		}
+	}
+
+	csvStr, err := TrendToCsv(report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(csvStr, "Build ID,Time,failed_tests,failure_rate,pass_rate,skip_rate,total_tests\n") || !strings.Contains(csvStr, "\n4,") {
+		t.Errorf("Unexpected trend CSV: %s", csvStr)
+	}
+}
+
+func TestComputeFieldTrendsSkipsMissingValues(t *testing.T) {
+	builds := []TrendBuild{
+		{BuildId: "1", Fields: map[string]float64{"total_tests": 10}},
+		{BuildId: "2", Fields: map[string]float64{"total_tests": 12, PassRateField: 90}},
+		{BuildId: "3", Fields: map[string]float64{"total_tests": 14, PassRateField: 80}},
+	}
+	trends := ComputeFieldTrends(builds)
+	passRate := trends[0]
+	if passRate.Field != PassRateField || passRate.Min != 80 || passRate.Mean != 85 || passRate.Slope != -10 || len(passRate.Values) != 2 {
+		t.Errorf("Expected the build without a pass rate to be left out, got %+v", passRate)
+	}
+
+	csvStr, err := TrendToCsv(TrendReport{Builds: builds, Fields: trends})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(csvStr, "\n1,,,10\n") {
+		t.Errorf("Expected an empty cell for the missing pass rate, got %s", csvStr)
+	}
+}
+
+func TestReportBuildTrendOutputDir(t *testing.T) {
+	store := mockStoreWithBuilds(t, map[string]map[string]interface{}{
+		"1": {"total_tests": 10, "failed_tests": 4},
+		"2": {"total_tests": 12, "failed_tests": 3},
+	})
+	outputDir := filepath.Join(t.TempDir(), "trend")
+	outputFile := filepath.Join(t.TempDir(), "output.env")
+	t.Setenv("HARNESS_PIPELINE_ID", mockPipelineId)
+	t.Setenv("HARNESS_BUILD_ID", "2")
+	t.Setenv("DRONE_OUTPUT", outputFile)
+
+	args := Args{Tool: JunitTool, GroupName: "suite_01", TrendBuilds: 5, DiffOutputDir: outputDir}
+	if _, err := ReportBuildTrend(context.Background(), args, store); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, fileName := range []string{BuildResultsTrendCsv, BuildResultsTrendJson} {
+		if _, err := os.Stat(filepath.Join(outputDir, fileName)); err != nil {
+			t.Errorf("Expected %s in the diff output directory, got %v", fileName, err)
+		}
+	}
+	output, _ := os.ReadFile(outputFile)
+	if !strings.Contains(string(output), TestResultsTrendFileOutputVar+"="+filepath.Join(outputDir, BuildResultsTrendCsv)) {
+		t.Errorf("Expected the trend file path to be exported, got %s", output)
+	}
+}
+
+func TestGetBuildTrendWindow(t *testing.T) {
+	store := NewMemoryResultStore()
+	query := BuildQuery{Measurement: JunitTool, PipelineId: mockPipelineId, Group: "suite_01"}
+	_ = PersistResults(context.Background(), store, JunitTool, "suite_01",
+		map[string]string{"pipelineId": mockPipelineId, "buildId": "1"}, map[string]interface{}{"total_tests": 1})
+	store.points[0].record.Time = time.Now().Add(-48 * time.Hour)
+	_ = store.WritePoint(context.Background(), JunitTool,
+		map[string]string{"pipelineId": mockPipelineId, "buildId": "2", "group": "suite_01"}, map[string]interface{}{"total_tests": 2})
+
+	report, err := GetBuildTrend(context.Background(), store, query, "2", 0, 24*time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Builds) != 1 || report.Builds[0].BuildId != "2" {
+		t.Errorf("Expected only build 2 within the window, got %+v", report.Builds)
+	}
+}
+
+func TestParseTrendWindow(t *testing.T) {
+	tests := []struct {
+		window    string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{"", 0, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"xd", 0, true},
+		{"-1h", 0, true},
+	}
+	for _, tt := range tests {
+		duration, err := ParseTrendWindow(tt.window)
+		if (err != nil) != tt.expectErr || duration != tt.expected {
+			t.Errorf("ParseTrendWindow(%q) = %v, %v", tt.window, duration, err)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]float64{1, 2, 3, 4, 5, 6, 7, 8}); got != "▁▂▃▄▅▆▇█" {
+		t.Errorf("Unexpected sparkline %s", got)
+	}
+	if got := Sparkline([]float64{5, 5}); got != "▁▁" {
+		t.Errorf("Unexpected flat sparkline %s", got)
+	}
+}
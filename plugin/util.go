@@ -5,22 +5,17 @@
 package plugin
 
 import (
+	"bufio"
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"github.com/bmatcuk/doublestar/v4"
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/sirupsen/logrus"
 	"io"
 	"math"
 	"os"
-	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
-	"time"
 )
 
 const (
@@ -31,7 +26,8 @@ const (
 	PipeLineIdEnvVar             = "HARNESS_PIPELINE_ID"
 	BuildNumberEnvVar            = "HARNESS_BUILD_ID"
 	TestResultsDiffFileOutputVar = "TEST_RESULTS_DIFF_FILE"
-	BuildResultsDiffCsv          = "build_results_diff.csv"
+
+	xmlReadBufferSize = 64 * 1024
 )
 
 type ResultBasicInfo struct {
@@ -42,11 +38,51 @@ type ResultBasicInfo struct {
 	Status     string
 }
 
+// AggregateResult holds the tags and fields an aggregator produced for the
+// current build, along with the failed tests and per package coverage when
+// the tool reports them.
+type AggregateResult struct {
+	Tool     string
+	Tags     map[string]string
+	Fields   map[string]interface{}
+	Failures []TestFailure
+	Packages []Package
+	Files    []FileResult
+	Modules  []ModuleResult
+	// Tests and Suites are the JUnit test results and suites.
+	Tests  []TestExecution
+	Suites []TestSuiteResult
+	// SkippedFiles are the matched report files that could not be parsed.
+	SkippedFiles []SkippedFile
+}
+
+// FileResult holds the fields aggregated from a single report file.
+type FileResult struct {
+	Path   string                 `json:"path"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type TestFailure struct {
+	ClassName  string `json:"class_name"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Type       string `json:"type,omitempty"`
+	StackTrace string `json:"stack_trace,omitempty"`
+	SystemOut  string `json:"system_out,omitempty"`
+	SystemErr  string `json:"system_err,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
 type DbCredentials struct {
-	InfluxDBURL   string
-	InfluxDBToken string
-	Organization  string
-	Bucket        string
+	InfluxDBURL     string
+	InfluxDBToken   string
+	Organization    string
+	Bucket          string
+	Database        string
+	RetentionPolicy string
+	Username        string
+	Password        string
 }
 
 type BuildResultCompare struct {
@@ -63,6 +99,11 @@ type ResultDiff struct {
 	PreviousBuildValue   float64 `json:"previous_build"`
 	Difference           float64 `json:"difference"`
 	PercentageDifference float64 `json:"percentage_difference"`
+	Direction            string  `json:"direction"`
+	Change               string  `json:"change"`
+	Baseline             string  `json:"baseline,omitempty"`
+	BaselineBuildId      int     `json:"baseline_build_id,omitempty"`
+	Module               string  `json:"module,omitempty"`
 	IsCompareValid       bool    `json:"-"`
 }
 
@@ -76,132 +117,124 @@ func GetNewBuildResultCompare(tool, currentPipelineId, currentBuildId, previousP
 	}
 }
 
-func GetXmlReportData[T any](reportsRootDir string, patterns []string) ([]T, error) {
+// GetXmlReportData parses the report files matching the patterns. Files that
+// cannot be parsed are returned as skipped with the reason, and the paths of
+// the parsed reports are relative to reportsRootDir.
+func GetXmlReportData[T any](reportsRootDir string, patterns []string, options ParseOptions) ([]T, []string, []SkippedFile, error) {
 
 	logrus.Println("GetXmlReportData: reportsRootDir ==  ", reportsRootDir)
 
-	var xmlReportFiles []string
+	var parsedReportFiles []string
 	var xmlFileReportDataList []T
 
-	for _, pattern := range patterns {
-		tmpReportDir := os.DirFS(reportsRootDir)
-		relPattern := strings.TrimPrefix(pattern, reportsRootDir+"/")
-		filesList, err := doublestar.Glob(tmpReportDir, relPattern)
-		if err != nil {
-			logrus.Println("Include patterns not found ", err.Error())
-			return xmlFileReportDataList, err
-		}
-		xmlReportFiles = append(xmlReportFiles, filesList...)
+	xmlReportFiles, err := DiscoverReportFiles(reportsRootDir, patterns, SplitPatterns(options.ExcludePattern))
+	if err != nil {
+		return xmlFileReportDataList, parsedReportFiles, nil, err
 	}
 
-	for _, xmlReportFile := range xmlReportFiles {
-		tmpXmlReportFile := filepath.Join(reportsRootDir, xmlReportFile)
-		report := ParseXmlReport[T](tmpXmlReportFile)
-		reportBytes, err := json.Marshal(report)
+	sources, skippedFiles, cleanup := ExpandReportSources(reportsRootDir, xmlReportFiles, options.ArchivePattern)
+	defer cleanup()
+
+	reports, errs := parseFiles(sources, options.ParseWorkers(), func(source ReportSource) (T, error) {
+		reader, err := source.Open()
 		if err != nil {
-			logrus.Printf("Error marshalling report: %v", err)
+			return *new(T), fmt.Errorf("error opening report: %w", err)
 		}
-
-		xmlFileReport, err := ToStructFromJsonString[T](string(reportBytes))
+		defer reader.Close()
+		return DecodeXmlReport[T](reader)
+	})
+	for i, source := range sources {
+		report, err := reports[i], errs[i]
 		if err != nil {
-			logrus.Printf("Error converting json to struct: %v", err)
-			return xmlFileReportDataList, err
+			logrus.Printf("Skipping report %s: %v", source.Path, err)
+			skippedFiles = append(skippedFiles, SkippedFile{Path: source.Path, Reason: err.Error()})
+			continue
 		}
-
-		xmlFileReportDataList = append(xmlFileReportDataList, xmlFileReport)
+		xmlFileReportDataList = append(xmlFileReportDataList, report)
+		parsedReportFiles = append(parsedReportFiles, source.Path)
 	}
 
-	return xmlFileReportDataList, nil
+	return xmlFileReportDataList, parsedReportFiles, skippedFiles, nil
 }
 
-func ParseXmlReport[T any](filename string) T {
+// ParseXmlReport decodes the report while reading the file, so the file is
+// never held in memory as a whole. Report types with an UnmarshalXML method
+// aggregate it token by token.
+func ParseXmlReport[T any](filename string) (T, error) {
+	var report T
 	file, err := os.Open(filename)
 	if err != nil {
 		logrus.Println("Error opening XML file: ", err)
-		logrus.Fatalf("Error opening XML file: %v", err)
+		return report, fmt.Errorf("error opening report: %w", err)
 	}
 	defer file.Close()
+	return DecodeXmlReport[T](file)
+}
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		logrus.Println("Error reading XML file ", err)
-	}
-
+// DecodeXmlReport decodes a report from a file, a decompressed file or an
+// archive entry.
+func DecodeXmlReport[T any](reader io.Reader) (T, error) {
 	var report T
-	err = xml.Unmarshal(data, &report)
+	decoder := xml.NewDecoder(bufio.NewReaderSize(reader, xmlReadBufferSize))
+	err := decoder.Decode(&report)
 	if err != nil {
-		logrus.Printf("Error unmarshalling XML: %v", err)
+		logrus.Printf("Error decoding XML: %v", err)
+		return report, fmt.Errorf("invalid XML: %w", err)
 	}
-	return report
+	return report, nil
 }
 
-func Aggregate[T any](reportsDir, includes string,
-	dbUrl, dbToken, dbOrg, dbBucket, measurementName, groupName string,
+// Aggregate parses the reports and aggregates each of them once for the
+// per-file results. The total is aggregated from those per-file aggregates,
+// so calculateAggregate must accept its own results.
+func Aggregate[T any](reportsDir, includes string, options ParseOptions,
 	calculateAggregate func(testNgAggregatorList []T) T,
 	getDataMaps func(pipelineId,
 		buildNumber string, aggregateData T) (map[string]string, map[string]interface{}),
 	showBuildStats func(tagsMap map[string]string,
-		fieldsMap map[string]interface{}) error) (map[string]string, map[string]interface{}, error) {
+		fieldsMap map[string]interface{}) error) (T, AggregateResult, error) {
 
-	tagsMap := map[string]string{}
-	fieldsMap := map[string]interface{}{}
+	var totalAggregate T
+	result := AggregateResult{Tags: map[string]string{}, Fields: map[string]interface{}{}}
 
 	reportsRootDir := reportsDir
-	patterns := strings.Split(includes, ",")
+	patterns := SplitPatterns(includes)
 
-	aggregatorList, err := GetXmlReportData[T](reportsRootDir, patterns)
+	aggregatorList, reportFiles, skippedFiles, err := GetXmlReportData[T](reportsRootDir, patterns, options)
 	if err != nil {
 		logrus.Println("Error getting xml report data: ", err.Error())
-		return tagsMap, fieldsMap, err
+		return totalAggregate, result, err
+	}
+	result.SkippedFiles = skippedFiles
+	err = CheckParsedReports(options, len(reportFiles)+len(skippedFiles), len(reportFiles), skippedFiles)
+	if err != nil {
+		return totalAggregate, result, err
 	}
-
-	totalAggregate := calculateAggregate(aggregatorList)
-	logrus.Println("Total Aggregate: ", totalAggregate)
 
 	pipelineId, buildNumber, err := GetPipelineInfo()
 	if err != nil {
 		logrus.Println("Error getting pipeline info: ", err.Error())
-		return tagsMap, fieldsMap, err
+		return totalAggregate, result, err
 	}
 
-	tagsMap, fieldsMap = getDataMaps(pipelineId, buildNumber, totalAggregate)
-	err = showBuildStats(tagsMap, fieldsMap)
-	if err != nil {
-		logrus.Println("Error showing build stats: ", err.Error())
-		return tagsMap, fieldsMap, err
+	fileAggregates := make([]T, len(aggregatorList))
+	for i, report := range aggregatorList {
+		fileAggregates[i] = calculateAggregate([]T{report})
+		_, fileFields := getDataMaps(pipelineId, buildNumber, fileAggregates[i])
+		result.Files = append(result.Files, FileResult{Path: reportFiles[i], Fields: fileFields})
 	}
+	totalAggregate = calculateAggregate(fileAggregates)
+	logrus.Println("Total Aggregate: ", totalAggregate)
 
-	if dbUrl != "" && dbToken != "" && dbOrg != "" && dbBucket != "" {
-		err = PersistToInfluxDb(dbUrl, dbToken, dbOrg, dbBucket, measurementName, groupName, tagsMap, fieldsMap)
-		if err != nil {
-			logrus.Println("Error persisting data to InfluxDB: ", err.Error())
-			return tagsMap, fieldsMap, err
-		}
-	}
-
-	return tagsMap, fieldsMap, err
-}
-
-func PersistToInfluxDb(dbUrl, dbToken, dbOrganisation, dbBucket, measurementName, groupName string,
-	tagsMap map[string]string, fieldsMap map[string]interface{}) error {
-
-	tagsMap["group"] = groupName
-	client := influxdb2.NewClient(dbUrl, dbToken)
-	defer client.Close()
-	writeAPI := client.WriteAPIBlocking(dbOrganisation, dbBucket)
+	result.Tags, result.Fields = getDataMaps(pipelineId, buildNumber, totalAggregate)
 
-	point := influxdb2.NewPoint(
-		measurementName,
-		tagsMap,
-		fieldsMap,
-		time.Now())
-	err := writeAPI.WritePoint(context.Background(), point)
+	err = showBuildStats(result.Tags, result.Fields)
 	if err != nil {
-		logrus.Println("Error writing point: ", err)
-		return err
+		logrus.Println("Error showing build stats: ", err.Error())
+		return totalAggregate, result, err
 	}
-	logrus.Println("Data persisted successfully to InfluxDB.")
-	return nil
+
+	return totalAggregate, result, err
 }
 
 func GetPipelineInfo() (string, string, error) {
@@ -215,164 +248,32 @@ func GetPipelineInfo() (string, string, error) {
 	return pipelineId, buildNumber, nil
 }
 
-func GetPreviousBuildId(measurementName, influxURL, token, org, bucket,
-	currentPipelineId, groupId, currentBuildId string, args Args) (int, error) {
-
-	if args.CompareBuildId != "" {
-		prevBuildId, convErr := strconv.Atoi(args.CompareBuildId)
-		if convErr != nil {
-			logrus.Println("Error converting previous build ID ", args.CompareBuildId, " to int: ", convErr)
-			return 0, fmt.Errorf("error converting previous build ID to int: %w", convErr)
-		}
-		fmt.Println("Comparing against build ID: ", prevBuildId)
-		return prevBuildId, nil
-	}
-
-	client := influxdb2.NewClient(influxURL, token)
-	defer client.Close()
-
-	query := fmt.Sprintf(`
-	from(bucket: "%s")
-	  |> range(start: -1y)
-	  |> filter(fn: (r) => r._measurement == "%s" and r.pipelineId == "%s" and r.group == "%s")
-	  |> pivot(rowKey:["_time"], columnKey: ["_field"], valueColumn: "_value")
-	  |> sort(columns: ["buildId"], desc: true)
-	`, bucket, measurementName, currentPipelineId, groupId)
-
-	queryAPI := client.QueryAPI(org)
-	result, err := queryAPI.Query(context.Background(), query)
-	if err != nil {
-		logrus.Println("Error querying InfluxDB: ", err)
-		return 0, fmt.Errorf("failed to query InfluxDB: %w", err)
-	}
-
-	currentBuild, err := strconv.Atoi(currentBuildId)
-	if err != nil {
-		logrus.Println("Invalid currentBuildId: ", currentBuildId)
-		return 0, fmt.Errorf("invalid currentBuildId: %s", currentBuildId)
-	}
-
-	var prevBuildId = 0
-	var buildId int
-	found := false
-	var convErr error
-
-	for result.Next() {
-		buildIdStr := result.Record().ValueByKey("buildId")
-		if buildIdStr == nil {
-			continue
-		}
-
-		prevBuildId = buildId
-		buildId, convErr = strconv.Atoi(buildIdStr.(string))
-		if convErr != nil {
-			logrus.Println("Error converting buildId ", buildIdStr, "to int error: ", convErr)
-			continue
-		}
-
-		if buildId >= currentBuild {
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		logrus.Println("No previous build ID found for ", currentBuild)
-		return 0, fmt.Errorf("no previous build ID found for %d", currentBuild)
-	}
-
-	fmt.Println("Previous build ID found: ", prevBuildId)
-	return prevBuildId, nil
-}
-
-func CompareResults(tool string, args Args) (string, error) {
-	var resultStr string
+func CompareResults(ctx context.Context, tool string, store ResultStore, args Args) ([]BaselineComparison, error) {
 	currentPipelineId, currentBuildNumber, err := GetPipelineInfo()
 	if err != nil {
 		fmt.Println("CompareResults Error getting pipeline info: ", err)
-		return resultStr, err
+		return nil, err
 	}
 
-	previousBuildId, err := GetPreviousBuildId(tool, args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket, currentPipelineId, args.GroupName, currentBuildNumber, args)
+	query := BuildQuery{Measurement: tool, PipelineId: currentPipelineId, Group: args.GroupName}
+	baselines, err := ResolveBaselines(ctx, store, query, currentBuildNumber, args)
 	if err != nil {
-		fmt.Println("CompareResults Error getting previous build id: ", err)
-		return resultStr, err
+		fmt.Println("CompareResults Error getting baseline builds: ", err)
+		return nil, err
 	}
 
-	resultStr, err = GetComparedDifferences(tool, args.DbUrl, args.DbToken, args.DbOrg, args.DbBucket, currentPipelineId, args.GroupName, currentBuildNumber, strconv.Itoa(previousBuildId))
+	comparisons, err := CompareWithBaselines(ctx, store, query, currentBuildNumber, baselines)
 	if err != nil {
 		fmt.Println("CompareResults Error getting compared differences: ", err)
-		return resultStr, err
+		return nil, err
 	}
-	return resultStr, nil
+	return comparisons, nil
 }
 
-func GetComparedDifferences(measurementName, influxURL, token, org, bucket, currentPipelineId, groupId, currentBuildId, previousBuildId string) (string, error) {
-	client := influxdb2.NewClient(influxURL, token)
-	defer client.Close()
-
-	currentValues, err := GetStoredBuildResults(client, org, bucket, measurementName, currentPipelineId, groupId, currentBuildId)
-	if err != nil {
-		fmt.Println("GetComparedDifferences Error fetching current build values: ", err)
-		return "", fmt.Errorf("error fetching current build values: %w", err)
-	}
-
-	previousValues, err := GetStoredBuildResults(client, org, bucket, measurementName, currentPipelineId, groupId, previousBuildId)
-	if err != nil {
-		fmt.Println("GetComparedDifferences Error fetching previous build values: ", err)
-		return "", fmt.Errorf("error fetching previous build values: %w", err)
-	}
-
-	diffStr, err := ComputeBuildResultDifferences(currentValues, previousValues)
-	if err != nil {
-		fmt.Println("GetComparedDifferences Error computing differences: ", err)
-		return "", err
-	}
-	return diffStr, nil
-}
-
-func GetStoredBuildResults(client influxdb2.Client, org, bucket, measurementName,
-	pipelineId, groupId, buildId string) (map[string]float64, error) {
-
-	query := fmt.Sprintf(`
-	from(bucket: "%s")
-	  |> range(start: -1y)
-	  |> filter(fn: (r) => r._measurement == "%s")
-	  |> filter(fn: (r) => r.pipelineId == "%s")
-	  |> filter(fn: (r) => r.group == "%s")
-	  |> filter(fn: (r) => r.buildId == "%s")
-	  |> keep(columns: ["_field", "_value"])
-	`, bucket, measurementName, pipelineId, groupId, buildId)
-
-	queryAPI := client.QueryAPI(org)
-	result, err := queryAPI.Query(context.Background(), query)
-	if err != nil {
-		fmt.Println("fetchBuildFieldValues Error querying InfluxDB: ", err)
-		return nil, fmt.Errorf("failed to query InfluxDB: %w", err)
-	}
-
-	fieldValues := make(map[string]float64)
-	for result.Next() {
-		fieldName := result.Record().ValueByKey("_field")
-		value := result.Record().ValueByKey("_value")
-
-		if fieldName == nil || value == nil {
-			continue
-		}
-
-		fieldNameStr := fieldName.(string)
-		valueFloat, convErr := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
-		if convErr != nil {
-			continue
-		}
-
-		fieldValues[fieldNameStr] = valueFloat
-	}
-
-	return fieldValues, nil
-}
-
-func ComputeBuildResultDifferences(currentValues, previousValues map[string]float64) (string, error) {
+// ComputeResultDiffs returns the difference of every field found in either
+// build, sorted by field name, and whether it is an improvement. IsCompareValid
+// is false when a field is missing from one of the builds.
+func ComputeResultDiffs(currentValues, previousValues map[string]float64) []ResultDiff {
 	allFields := make(map[string]struct{})
 
 	for field := range currentValues {
@@ -382,57 +283,42 @@ func ComputeBuildResultDifferences(currentValues, previousValues map[string]floa
 		allFields[field] = struct{}{}
 	}
 
-	var csvBuffer strings.Builder
-	writer := csv.NewWriter(&csvBuffer)
-
-	err := writer.Write([]string{"Field Name", "Current", "Previous", "Difference", "Percentage Difference"})
-	if err != nil {
-		logrus.Println("Error writing to CSV writer: ", err)
-		return "", err
+	var sortedFields []string
+	for field := range allFields {
+		sortedFields = append(sortedFields, field)
 	}
+	sort.Strings(sortedFields)
 
-	for field := range allFields {
+	var diffs []ResultDiff
+	for _, field := range sortedFields {
 		currentValue, currentExists := currentValues[field]
 		previousValue, previousExists := previousValues[field]
 
-		if !currentExists {
-			currentValue = 0
-		}
-		if !previousExists {
-			previousValue = 0
-		}
-
-		diff := currentValue - previousValue
-		percentageDiff := 0.0
-		if previousValue != 0 {
-			percentageDiff = (diff / math.Abs(previousValue)) * 100
-		}
-
-		err = writer.Write([]string{
-			field,
-			fmt.Sprintf("%.2f", currentValue),
-			fmt.Sprintf("%.2f", previousValue),
-			fmt.Sprintf("%.2f", diff),
-			fmt.Sprintf("%.2f%%", percentageDiff),
+		direction := FieldDirection(field)
+		diffs = append(diffs, ResultDiff{
+			FieldName:            field,
+			CurrentBuildValue:    currentValue,
+			PreviousBuildValue:   previousValue,
+			Difference:           currentValue - previousValue,
+			PercentageDifference: computePercentageDiff(currentValue, previousValue),
+			Direction:            direction,
+			Change:               ChangeForDirection(direction, currentValue-previousValue),
+			IsCompareValid:       currentExists && previousExists,
 		})
-		if err != nil {
-			logrus.Println("Error writing to CSV writer: ", err)
-			return "", err
-		}
 	}
+	return diffs
+}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		fmt.Println("Error flushing CSV writer:", err)
-		return "", err
+func resultDiffRecord(diff ResultDiff) []string {
+	return []string{
+		diff.FieldName,
+		fmt.Sprintf("%.2f", diff.CurrentBuildValue),
+		fmt.Sprintf("%.2f", diff.PreviousBuildValue),
+		fmt.Sprintf("%.2f", diff.Difference),
+		fmt.Sprintf("%.2f%%", diff.PercentageDifference),
+		diff.Direction,
+		diff.Change,
 	}
-
-	fmt.Println("")
-	fmt.Println("Comparison results with previous build:")
-	ShowDiffAsTable(currentValues, previousValues)
-	fmt.Println("")
-
-	return csvBuffer.String(), nil
 }
 
 func ShowDiffAsTable(currentValues, previousValues map[string]float64) {
@@ -461,19 +347,19 @@ func ShowDiffAsTable(currentValues, previousValues map[string]float64) {
 			maxFieldLen = fieldLen
 		}
 
-		currentValStr := fmt.Sprintf("%d", int(currentValues[field]))
-		//previousValStr := fmt.Sprintf("%d", int(previousValues[field]))
-		diffStr := fmt.Sprintf("%d", int(currentValues[field]-previousValues[field]))
+		currentValStr := formatMarkdownNumber(currentValues[field])
+		previousValStr := formatMarkdownNumber(previousValues[field])
+		diffStr := formatMarkdownNumber(currentValues[field] - previousValues[field])
 		percentageDiffStr := fmt.Sprintf("%.2f%%", computePercentageDiff(currentValues[field], previousValues[field]))
 
-		maxValueLen = max(maxValueLen, len(currentValStr)) //, len(previousValStr))
+		maxValueLen = max(maxValueLen, len(currentValStr), len(previousValStr))
 		maxDiffLen = max(maxDiffLen, len(diffStr))
 		maxPercentDiffLen = max(maxPercentDiffLen, len(percentageDiffStr))
 	}
 
 	headerFormat := fmt.Sprintf("| %%-%ds | %%-%ds | %%-%ds | %%-%ds | %%-%ds |\n",
 		maxFieldLen, maxValueLen, maxValueLen, maxDiffLen, maxPercentDiffLen)
-	rowFormat := fmt.Sprintf("| %%-%ds | %%-%dd | %%-%dd | %%-%dd | %%-%ds |\n",
+	rowFormat := fmt.Sprintf("| %%-%ds | %%-%ds | %%-%ds | %%-%ds | %%-%ds |\n",
 		maxFieldLen, maxValueLen, maxValueLen, maxDiffLen, maxPercentDiffLen)
 
 	fmt.Println(strings.Repeat("-", maxFieldLen+maxValueLen*2+maxDiffLen+maxPercentDiffLen+14))
@@ -481,12 +367,10 @@ func ShowDiffAsTable(currentValues, previousValues map[string]float64) {
 	fmt.Println(strings.Repeat("-", maxFieldLen+maxValueLen*2+maxDiffLen+maxPercentDiffLen+14))
 
 	for _, field := range sortedFields {
-		currentVal := int(currentValues[field])
-		previousVal := int(previousValues[field])
-		diff := currentVal - previousVal
 		percentageDiff := computePercentageDiff(currentValues[field], previousValues[field])
 
-		fmt.Printf(rowFormat, field, currentVal, previousVal, diff, fmt.Sprintf("%.2f%%", percentageDiff))
+		fmt.Printf(rowFormat, field, formatMarkdownNumber(currentValues[field]), formatMarkdownNumber(previousValues[field]),
+			formatMarkdownNumber(currentValues[field]-previousValues[field]), fmt.Sprintf("%.2f%%", percentageDiff))
 	}
 
 	fmt.Println(strings.Repeat("-", maxFieldLen+maxValueLen*2+maxDiffLen+maxPercentDiffLen+14))